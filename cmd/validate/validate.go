@@ -1,31 +1,366 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"sort"
+	"text/tabwriter"
 
 	wdlparser "github.com/yunhailuo/wdlparser/pkg"
 )
 
+// output is where formatted documents, JSON reports and summaries are printed
+var output io.Writer = os.Stdout
+
+// exit terminates the program with a status code
+var exit = os.Exit
+
+// A report is the validation result of one WDL document in JSON output.
+type report struct {
+	Path   string                  `json:"path"`
+	Valid  bool                    `json:"valid"`
+	Errors []wdlparser.SyntaxError `json:"errors"`
+}
+
+// A fileReport is the validation result of one WDL document in a combined
+// report, including diagnostics of linting it if it's valid.
+type fileReport struct {
+	Path        string                  `json:"path"`
+	Version     string                  `json:"version"`
+	Valid       bool                    `json:"valid"`
+	Errors      []wdlparser.SyntaxError `json:"errors"`
+	Diagnostics []wdlparser.Diagnostic  `json:"diagnostics"`
+}
+
+// A combinedReport is the validation result of all WDL documents.
+type combinedReport struct {
+	Files   []fileReport `json:"files"`
+	Summary struct {
+		Files       int            `json:"files"`
+		Valid       int            `json:"valid"`
+		Diagnostics map[string]int `json:"diagnostics"`
+	} `json:"summary"`
+}
+
 func main() {
-	var path string
-	flag.StringVar(&path, "wdl", "", "path to a WDL document to be validated")
-	flag.Parse()
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		render(os.Args[2:])
+		return
+	}
 
-	f, err := os.Stat(path)
-	if os.IsNotExist(err) || f.IsDir() {
-		log.Printf("%v is not a path to a valid file\n\n", path)
-		flag.Usage()
-		os.Exit(1)
+	var path, reportFormat string
+	var format, write, jsonOutput, summary bool
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(
+			flags.Output(),
+			"Usage: %s [flags] [path ...]\n"+
+				"       %s render [flags] path\n\nFlags:\n",
+			flags.Name(),
+			flags.Name(),
+		)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&path, "wdl", "", "path to a WDL document to be validated")
+	flags.BoolVar(
+		&format, "format", false, "print the formatted WDL document",
+	)
+	flags.BoolVar(
+		&write,
+		"w",
+		false,
+		"with -format, write the formatted WDL document back to its file, "+
+			"unless comments would be dropped",
+	)
+	flags.BoolVar(
+		&jsonOutput, "json", false, "print validation results as JSON",
+	)
+	flags.BoolVar(
+		&summary,
+		"summary",
+		false,
+		"lint valid WDL documents and print diagnostic counts by rule",
+	)
+	flags.StringVar(
+		&reportFormat,
+		"report",
+		"",
+		"lint valid WDL documents and print a combined report "+
+			"in a format: json",
+	)
+	flags.Parse(os.Args[1:])
+
+	paths := flags.Args()
+	if path != "" {
+		paths = append([]string{path}, paths...)
+	}
+	if len(paths) == 0 {
+		log.Printf("no path to a WDL document is given\n\n")
+		flags.Usage()
+		exit(1)
+		return
+	}
+	if jsonOutput && format && !write {
+		log.Printf("-json can only be used with -format if -w is set\n\n")
+		flags.Usage()
+		exit(1)
+		return
+	}
+	if summary && (jsonOutput || format && !write) {
+		log.Printf(
+			"-summary can't be used with -json, or -format without -w\n\n",
+		)
+		flags.Usage()
+		exit(1)
+		return
+	}
+
+	if reportFormat != "" &&
+		(reportFormat != "json" || jsonOutput || summary || format && !write) {
+		log.Printf(
+			"-report only supports json, and can't be used with -json, " +
+				"-summary, or -format without -w\n\n",
+		)
+		flags.Usage()
+		exit(1)
+		return
+	}
+
+	reports := []report{}
+	combined := combinedReport{Files: []fileReport{}}
+	failed := false
+	lint := summary || reportFormat != ""
+	var allDiags []wdlparser.Diagnostic
+	for _, path := range paths {
+		r := report{path, true, []wdlparser.SyntaxError{}}
+		var diags *[]wdlparser.Diagnostic
+		if lint {
+			diags = &[]wdlparser.Diagnostic{}
+		}
+		quiet := jsonOutput || reportFormat != ""
+		wdl, ok := validate(&r, format, write, quiet, diags)
+		if !ok {
+			failed = true
+		}
+		reports = append(reports, r)
+		if lint {
+			allDiags = append(allDiags, *diags...)
+			fr := fileReport{
+				r.Path, "", r.Valid, r.Errors, append(
+					[]wdlparser.Diagnostic{}, *diags...,
+				),
+			}
+			if wdl != nil {
+				fr.Version = wdl.Version
+			}
+			combined.Files = append(combined.Files, fr)
+			if r.Valid {
+				combined.Summary.Valid++
+			}
+		}
+	}
+
+	if summary {
+		printSummary(wdlparser.Summarize(allDiags))
+	}
+	if reportFormat == "json" {
+		combined.Summary.Files = len(combined.Files)
+		combined.Summary.Diagnostics = wdlparser.Summarize(allDiags)
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(combined); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			log.Fatal(err)
+		}
 	}
+	if failed {
+		exit(1)
+	}
+}
+
+// render prints the command of a task in a WDL document with placeholders
+// evaluated against inputs in a JSON file.
+func render(args []string) {
+	var task, inputsPath string
+	flags := flag.NewFlagSet(os.Args[0]+" render", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(
+			flags.Output(),
+			"Usage: %s [flags] path\n\nFlags:\n",
+			flags.Name(),
+		)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(
+		&task,
+		"task",
+		"",
+		"name of the task to render, optional if there's only one task",
+	)
+	flags.StringVar(&inputsPath, "inputs", "", "path to an inputs JSON")
+	flags.Parse(args)
 
-	_, errs := wdlparser.Antlr4Parse(path)
+	if flags.NArg() != 1 {
+		log.Printf("one path to a WDL document is required\n\n")
+		flags.Usage()
+		exit(1)
+		return
+	}
+	path := flags.Arg(0)
+	wdl, errs := wdlparser.ParseFile(path, wdlparser.ParseOptions{})
 	if errs != nil {
 		log.Printf(
 			"Invalid WDL (%q): found %d syntax errors.\n", path, len(errs),
 		)
-	} else {
-		log.Printf("WDL (%q) is valid.\n", path)
+		for _, e := range errs {
+			log.Printf("%s: %v\n", path, e)
+		}
+		exit(1)
+		return
+	}
+	inputs := map[string]interface{}{}
+	if inputsPath != "" {
+		data, err := os.ReadFile(inputsPath)
+		if err == nil {
+			err = json.Unmarshal(data, &inputs)
+		}
+		if err != nil {
+			log.Printf("Failed to read inputs (%q): %v\n", inputsPath, err)
+			exit(1)
+			return
+		}
+	}
+	command, err := wdl.RenderCommand(task, inputs)
+	if err != nil {
+		log.Printf("Failed to render command of %q: %v\n", path, err)
+		exit(1)
+		return
+	}
+	fmt.Fprintln(output, command)
+}
+
+// printSummary prints a table of diagnostic counts by rule.
+func printSummary(counts map[string]int) {
+	rules := make([]string, 0, len(counts))
+	for rule := range counts {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	w := tabwriter.NewWriter(output, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RULE\tCOUNT")
+	for _, rule := range rules {
+		fmt.Fprintf(w, "%s\t%d\n", rule, counts[rule])
+	}
+	w.Flush()
+}
+
+// validate parses, and optionally formats, one WDL document. It records the
+// result in a report and returns the parsed document, if any, and whether the
+// document is processed without any error. If diags isn't nil, the document
+// is also linted and diagnostics are appended to diags.
+func validate(
+	r *report, format, write, quiet bool, diags *[]wdlparser.Diagnostic,
+) (*wdlparser.WDL, bool) {
+	f, err := os.Stat(r.Path)
+	if os.IsNotExist(err) || f.IsDir() {
+		fail(
+			r,
+			wdlparser.CodeUnreadable,
+			fmt.Sprintf("%v is not a path to a valid file", r.Path),
+			quiet,
+		)
+		return nil, false
+	}
+
+	wdl, errs := wdlparser.ParseFile(r.Path, wdlparser.ParseOptions{})
+	if errs != nil {
+		r.Valid = false
+		r.Errors = append(r.Errors, errs...)
+		if !quiet {
+			log.Printf(
+				"Invalid WDL (%q): found %d syntax errors.\n",
+				r.Path,
+				len(errs),
+			)
+			for _, e := range errs {
+				log.Printf("%s: %v\n", r.Path, e)
+			}
+		}
+		return wdl, false
+	}
+	if diags != nil {
+		if err := wdl.ResolveImports(); err != nil && !quiet {
+			log.Printf("Failed to resolve imports of %q: %v\n", r.Path, err)
+		}
+		*diags = append(*diags, wdlparser.Lint(wdl)...)
+	}
+	if !format {
+		if !quiet {
+			log.Printf("WDL (%q) is valid.\n", r.Path)
+		}
+		return wdl, true
+	}
+
+	formatted, err := wdlparser.Format(wdl)
+	if err != nil {
+		fail(
+			r,
+			wdlparser.CodeUnformatted,
+			fmt.Sprintf("Failed to format WDL (%q): %v", r.Path, err),
+			quiet,
+		)
+		return wdl, false
+	}
+	if write {
+		// Refuse to rewrite a file rather than to lose its comments.
+		dropped := wdlparser.DroppedComments(wdl, formatted)
+		if dropped != nil {
+			fail(
+				r,
+				wdlparser.CodeUnformatted,
+				fmt.Sprintf(
+					"Refused to write formatted WDL (%q): %d comments, "+
+						"like %q, would be dropped",
+					r.Path,
+					len(dropped),
+					dropped[0],
+				),
+				quiet,
+			)
+			return wdl, false
+		}
+		err := os.WriteFile(r.Path, []byte(formatted), f.Mode())
+		if err != nil {
+			fail(r, wdlparser.CodeUnformatted, err.Error(), quiet)
+			return wdl, false
+		}
+		return wdl, true
+	}
+	fmt.Fprint(output, formatted)
+	return wdl, true
+}
+
+// fail records an error of a document on anything but its syntax, like
+// failing to be read or formatted, in its report, which is then invalid.
+func fail(r *report, code, msg string, quiet bool) {
+	r.Valid = false
+	r.Errors = append(r.Errors, wdlparser.SyntaxError{
+		Msg:      msg,
+		Code:     code,
+		Severity: wdlparser.SeverityError,
+	})
+	if !quiet {
+		log.Println(msg)
 	}
 }
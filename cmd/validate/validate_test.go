@@ -2,10 +2,17 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	wdlparser "github.com/yunhailuo/wdlparser/pkg"
 )
 
 func TestCLIvalidate(t *testing.T) {
@@ -43,3 +50,403 @@ func TestCLIvalidate(t *testing.T) {
 		}
 	}
 }
+
+func TestCLIinvalid(t *testing.T) {
+	buf := new(bytes.Buffer)
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	defer func() { log.SetOutput(os.Stderr) }()
+	defer func() { exit = os.Exit }()
+	log.SetOutput(buf)
+	code := 0
+	exit = func(c int) { code = c }
+
+	os.Args = []string{"./validate", "-wdl", "testdata/invalid.wdl"}
+	main()
+	pattern := `Invalid WDL \("testdata/invalid.wdl"\): found 1 syntax errors.\n` +
+		`.* testdata/invalid.wdl: line 6:4 "no viable alternative at input` +
+		` 'String\\\\n    }'"\n`
+	matched, err := regexp.MatchString(pattern, buf.String())
+	if err != nil {
+		t.Fatal("Expected pattern did not compile:", err)
+	}
+	if !matched {
+		t.Errorf("Stdout should match %q is %q", pattern, buf.String())
+	}
+	if code != 1 {
+		t.Errorf("Exit code should be 1 is %d", code)
+	}
+}
+
+func TestCLIformat(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	defer func() { output = os.Stdout }()
+	path := "../../pkg/testdata/task_runtime.wdl"
+	expected := `version 1.1
+
+task Runtime {
+    runtime {
+        container: "ubuntu:latest"
+    }
+}
+`
+
+	buf := new(bytes.Buffer)
+	output = buf
+	os.Args = []string{"./validate", "-format", "-wdl", path}
+	main()
+	if buf.String() != expected {
+		t.Errorf("Stdout should be %q is %q", expected, buf.String())
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := filepath.Join(t.TempDir(), "task_runtime.wdl")
+	if err := os.WriteFile(tmp, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	os.Args = []string{"./validate", "-format", "-w", "-wdl", tmp}
+	main()
+	if buf.Len() != 0 {
+		t.Errorf("Stdout should be empty is %q", buf.String())
+	}
+	rewritten, err := os.ReadFile(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rewritten) != expected {
+		t.Errorf("Rewritten file should be %q is %q", expected, rewritten)
+	}
+}
+
+func TestCLIformatDropsComments(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	defer func() { output = os.Stdout }()
+	defer func() { log.SetOutput(os.Stderr) }()
+	defer func() { exit = os.Exit }()
+	buf := new(bytes.Buffer)
+	output = buf
+	log.SetOutput(buf)
+	code := 0
+	exit = func(c int) { code = c }
+
+	src, err := os.ReadFile("../../pkg/testdata/comment.wdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := filepath.Join(t.TempDir(), "comment.wdl")
+	if err := os.WriteFile(tmp, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Args = []string{"./validate", "-format", "-w", "-wdl", tmp}
+	main()
+	pattern := `Refused to write formatted WDL \(".*comment.wdl"\): ` +
+		`2 comments, like "# not leading", would be dropped\n`
+	matched, err := regexp.MatchString(pattern, buf.String())
+	if err != nil {
+		t.Fatal("Expected pattern did not compile:", err)
+	}
+	if !matched {
+		t.Errorf("Stdout should match %q is %q", pattern, buf.String())
+	}
+	rewritten, err := os.ReadFile(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rewritten) != string(src) {
+		t.Errorf("File should be left as is, but is %q", rewritten)
+	}
+	if code != 1 {
+		t.Errorf("Exit code should be 1 is %d", code)
+	}
+}
+
+func TestCLIjson(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	defer func() { output = os.Stdout }()
+	defer func() { exit = os.Exit }()
+	buf := new(bytes.Buffer)
+	output = buf
+	code := 0
+	exit = func(c int) { code = c }
+
+	os.Args = []string{
+		"./validate",
+		"-json",
+		"../../pkg/testdata/version1_1.wdl",
+		"testdata/invalid.wdl",
+	}
+	main()
+	var reports []report
+	if err := json.Unmarshal(buf.Bytes(), &reports); err != nil {
+		t.Fatalf("Stdout should be JSON is %q: %v", buf.String(), err)
+	}
+	expected := []report{
+		{
+			"../../pkg/testdata/version1_1.wdl",
+			true,
+			[]wdlparser.SyntaxError{},
+		},
+		{
+			"testdata/invalid.wdl",
+			false,
+			[]wdlparser.SyntaxError{
+				{
+					Line:      6,
+					Column:    4,
+					EndLine:   6,
+					EndColumn: 5,
+					Token:     "}",
+					Stop:      63,
+					Msg: `no viable alternative at input ` +
+						`'String\n    }'`,
+					Code:     wdlparser.CodeSyntax,
+					Severity: wdlparser.SeverityError,
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, reports); diff != "" {
+		t.Errorf("unexpected JSON reports:\n%s", diff)
+	}
+	if code != 1 {
+		t.Errorf("Exit code should be 1 is %d", code)
+	}
+}
+
+func TestCLIjsonFormatFailure(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	defer func() { output = os.Stdout }()
+	defer func() { exit = os.Exit }()
+	buf := new(bytes.Buffer)
+	output = buf
+	code := 0
+	exit = func(c int) { code = c }
+
+	src, err := os.ReadFile("../../pkg/testdata/comment.wdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp := filepath.Join(t.TempDir(), "comment.wdl")
+	if err := os.WriteFile(tmp, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Args = []string{"./validate", "-json", "-format", "-w", tmp}
+	main()
+	var reports []report
+	if err := json.Unmarshal(buf.Bytes(), &reports); err != nil {
+		t.Fatalf("Stdout should be JSON is %q: %v", buf.String(), err)
+	}
+	expected := []report{
+		{
+			tmp,
+			false,
+			[]wdlparser.SyntaxError{
+				{
+					Msg: `Refused to write formatted WDL (` +
+						strconv.Quote(tmp) + `): 2 comments, ` +
+						`like "# not leading", would be dropped`,
+					Code:     wdlparser.CodeUnformatted,
+					Severity: wdlparser.SeverityError,
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, reports); diff != "" {
+		t.Errorf("unexpected JSON reports:\n%s", diff)
+	}
+	if code != 1 {
+		t.Errorf("Exit code should be 1 is %d", code)
+	}
+}
+
+func TestCLIsummary(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	defer func() { output = os.Stdout }()
+	buf := new(bytes.Buffer)
+	output = buf
+
+	os.Args = []string{
+		"./validate",
+		"-summary",
+		"../../pkg/testdata/runtime_container.wdl",
+		"../../pkg/testdata/workflow_output.wdl",
+	}
+	main()
+	expected := `RULE                    COUNT
+AbsolutePathDefault     1
+InconsistentRuntimeKey  1
+`
+	if buf.String() != expected {
+		t.Errorf("Stdout should be %q is %q", expected, buf.String())
+	}
+}
+
+func TestCLIreportJSON(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	defer func() { output = os.Stdout }()
+	defer func() { exit = os.Exit }()
+	buf := new(bytes.Buffer)
+	output = buf
+	code := 0
+	exit = func(c int) { code = c }
+
+	os.Args = []string{
+		"./validate",
+		"-report",
+		"json",
+		"../../pkg/testdata/workflow_output.wdl",
+		"testdata/invalid.wdl",
+	}
+	main()
+	var r combinedReport
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("Stdout should be JSON is %q: %v", buf.String(), err)
+	}
+	expected := combinedReport{
+		Files: []fileReport{
+			{
+				"../../pkg/testdata/workflow_output.wdl",
+				"1.1",
+				true,
+				[]wdlparser.SyntaxError{},
+				[]wdlparser.Diagnostic{
+					{
+						Rule:  wdlparser.AbsolutePathDefault,
+						Start: 52,
+						End:   87,
+						Msg: `"output_file" defaults to non-portable ` +
+							`absolute path "/Path/to/output"`,
+						Code:     "WDL101",
+						Severity: wdlparser.SeverityWarning,
+					},
+				},
+			},
+			{
+				"testdata/invalid.wdl",
+				"1.1",
+				false,
+				[]wdlparser.SyntaxError{
+					{
+						Line:      6,
+						Column:    4,
+						EndLine:   6,
+						EndColumn: 5,
+						Token:     "}",
+						Stop:      63,
+						Msg: `no viable alternative at input ` +
+							`'String\n    }'`,
+						Code:     wdlparser.CodeSyntax,
+						Severity: wdlparser.SeverityError,
+					},
+				},
+				[]wdlparser.Diagnostic{},
+			},
+		},
+	}
+	expected.Summary.Files = 2
+	expected.Summary.Valid = 1
+	expected.Summary.Diagnostics = map[string]int{
+		wdlparser.AbsolutePathDefault: 1,
+	}
+	if diff := cmp.Diff(expected, r); diff != "" {
+		t.Errorf("unexpected JSON report:\n%s", diff)
+	}
+	if code != 1 {
+		t.Errorf("Exit code should be 1 is %d", code)
+	}
+}
+
+func TestCLIrender(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	defer func() { output = os.Stdout }()
+	defer func() { exit = os.Exit }()
+	buf := new(bytes.Buffer)
+	output = buf
+	code := 0
+	exit = func(c int) { code = c }
+
+	inputsPath := filepath.Join(t.TempDir(), "inputs.json")
+	err := os.WriteFile(
+		inputsPath, []byte(`{"CommandPlaceholder.world": "earth"}`), 0644,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Args = []string{
+		"./validate",
+		"render",
+		"-task",
+		"CommandPlaceholder",
+		"-inputs",
+		inputsPath,
+		"../../pkg/testdata/task_command_placeholder.wdl",
+	}
+	main()
+	expected := `echo "Hello earth"`
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("Stdout should contain %q is %q", expected, buf.String())
+	}
+	if code != 0 {
+		t.Errorf("Exit code should be 0 is %d", code)
+	}
+}
+
+func TestCLIreportFormatFailure(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	defer func() { output = os.Stdout }()
+	defer func() { exit = os.Exit }()
+	buf := new(bytes.Buffer)
+	output = buf
+	code := 0
+	exit = func(c int) { code = c }
+
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"task_runtime.wdl", "comment.wdl"} {
+		src, err := os.ReadFile(filepath.Join("../../pkg/testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	os.Args = append(
+		[]string{"./validate", "-report", "json", "-format", "-w"}, paths...,
+	)
+	main()
+	var r combinedReport
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("Stdout should be JSON is %q: %v", buf.String(), err)
+	}
+	var valid []bool
+	for _, f := range r.Files {
+		valid = append(valid, f.Valid)
+	}
+	if diff := cmp.Diff([]bool{true, false}, valid); diff != "" {
+		t.Errorf("unexpected validity of files:\n%s", diff)
+	}
+	if r.Summary.Files != 2 || r.Summary.Valid != 1 {
+		t.Errorf(
+			"Summary should count 1 of 2 files as valid, is %d of %d",
+			r.Summary.Valid, r.Summary.Files,
+		)
+	}
+	if code != 1 {
+		t.Errorf("Exit code should be 1 is %d", code)
+	}
+}
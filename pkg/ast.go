@@ -2,28 +2,74 @@ package wdlparser
 
 import (
 	"path"
+	"sort"
 	"strings"
+	"sync/atomic"
 )
 
+// A Node is any node of a parsed WDL document. It can be type switched to its
+// concrete type, like *Workflow, *Scatter, *Call, *Task or *WDL itself.
+//
+// Positions of nodes are 0-based offsets in characters, which are Unicode code
+// points, rather than bytes of the UTF-8 source, so a position never points
+// into the middle of a multibyte character. See WDL.ByteSpan for byte offsets.
+type Node interface {
+	Start() int // position of first character belonging to the node, 0-based
+	End() int   // position of last character belonging to the node, 0-based
+	ID() NodeID
+	Parent() Node // nil for a document
+	LeadingComments() []string
+}
+
+// A node is a Node as built by the parser, which links it to its parent.
 type node interface {
-	getStart() int // position of first character belonging to the node, 0-based
-	getEnd() int   // position of last character belonging to the node, 0-based
+	Node
+
+	getStart() int
+	getEnd() int
+	setID(NodeID)
 
 	getParent() node
 	setParent(node)
+
+	addComment(string)
 }
 
+// A NodeID identifies a node across documents. Nodes of a parsed document
+// are given unique IDs, and a node left unchanged by Reparse keeps its ID.
+type NodeID uint64
+
+// lastNodeID is the most recent NodeID given to a node.
+var lastNodeID uint64
+
+func newNodeID() NodeID { return NodeID(atomic.AddUint64(&lastNodeID, 1)) }
+
 // A genNode is a concrete type of the node interface.
 type genNode struct {
 	start, end int
+	id         NodeID
 	parent     node
+	comments   []string
 }
 
+func (v *genNode) Start() int            { return v.start }
+func (v *genNode) End() int              { return v.end }
 func (v *genNode) getStart() int         { return v.start }
 func (v *genNode) getEnd() int           { return v.end }
+func (v *genNode) ID() NodeID            { return v.id }
+func (v *genNode) setID(id NodeID)       { v.id = id }
 func (v *genNode) getParent() node       { return v.parent }
 func (v *genNode) setParent(parent node) { v.parent = parent }
 
+// Parent returns the node a node is in, like the task of a declaration or the
+// declaration of an expression.
+func (v *genNode) Parent() Node {
+	if v.parent == nil {
+		return nil
+	}
+	return v.parent
+}
+
 type identifier struct {
 	initialName string
 	isReference bool // otherwise, this is a definition
@@ -58,6 +104,8 @@ type valueSpec struct {
 	name  *identifier
 	typ   string
 	value *exprRPN
+	raw   string      // source text of the value
+	meta  interface{} // go value of metadata
 }
 
 func newValueSpec(start, end int, identifier, rawType string) *valueSpec {
@@ -78,7 +126,10 @@ type WDL struct {
 	Imports  []*importSpec
 	Workflow *Workflow
 	Tasks    []*Task
-	Structs  []*valueSpec
+	Structs  []*Struct
+	Warnings []Diagnostic // on valid but deprecated constructs
+
+	source string // parsed source text, for Reparse and Source
 }
 
 func NewWDL(wdlPath string, size int) *WDL {
@@ -92,63 +143,180 @@ func NewWDL(wdlPath string, size int) *WDL {
 	return wdl
 }
 
+// Source returns the source text of a node of a parsed document, exactly as
+// it's written. It returns an empty string for a node without a position in
+// the source, like one of another document.
+func (w *WDL) Source(n Node) string {
+	src := []rune(w.source)
+	start, end := n.Start(), n.End()
+	if start < 0 || end < start || end >= len(src) {
+		return ""
+	}
+	return string(src[start : end+1])
+}
+
+// ByteSpan returns byte offsets of a node of a parsed document in its UTF-8
+// source, from the first byte of the node to the byte right after it, so
+// that source[start:end] is the source text of the node. The start is -1 for
+// a node beyond the source.
+func (w *WDL) ByteSpan(n Node) (start, end int) {
+	chars := 0
+	start, end = -1, len(w.source)
+	for i := range w.source {
+		switch chars {
+		case n.Start():
+			start = i
+		case n.End() + 1:
+			return start, i
+		}
+		chars++
+	}
+	return start, end
+}
+
 type importSpec struct {
 	namedNode
 	uri           *exprRPN
 	importAliases map[string]string // key is original name and value is alias
+	wdl           *WDL              // imported document, set by ResolveImports
 }
 
 func newImportSpec(start, end int, parent node, uri string) *importSpec {
 	is := new(importSpec)
-	is.setParent(parent)
 	v := make(exprRPN, 0)
 	is.uri = &v
 	is.namedNode = *newNamedNode(
 		start, end, strings.TrimSuffix(path.Base(uri), ".wdl"),
 	)
+	is.setParent(parent)
 	is.importAliases = map[string]string{}
 	return is
 }
 
+// A Struct represents one parsed struct definition.
+type Struct struct {
+	namedNode
+	Members []*valueSpec
+}
+
+func NewStruct(start, end int, parent node, name string) *Struct {
+	s := new(Struct)
+	s.namedNode = *newNamedNode(start, end, name)
+	s.setParent(parent)
+	return s
+}
+
 // A Workflow represents one parsed workflow.
 type Workflow struct {
 	namedNode
+	bodyStart, bodyEnd int // positions of the opening and closing braces
+
 	Inputs        []*valueSpec
-	PrvtDecls     []*valueSpec
+	PrvtDecls     []*valueSpec // including those in scatters and conditionals
 	Outputs       []*valueSpec
-	Calls         []*Call
+	Calls         []*Call // including those in scatters and conditionals
+	Blocks        []node  // top level scatters and conditionals
 	Meta          []*valueSpec
 	ParameterMeta []*valueSpec
+
+	HasOutputSection bool // whether there's an output section, even if empty
 }
 
 func NewWorkflow(start, end int, parent node, name string) *Workflow {
 	workflow := new(Workflow)
-	workflow.setParent(parent)
 	workflow.namedNode = *newNamedNode(start, end, name)
+	workflow.setParent(parent)
 	return workflow
 }
 
+// BodySpan returns the positions of the opening and closing braces of the
+// workflow body.
+func (w *Workflow) BodySpan() (start, end int) {
+	return w.bodyStart, w.bodyEnd
+}
+
+// QualifiedOutputs returns outputs of a workflow by their fully qualified
+// names, like Workflow.output, as workflow engines name them.
+func (w *Workflow) QualifiedOutputs() map[string]*valueSpec {
+	outputs := map[string]*valueSpec{}
+	for _, output := range w.Outputs {
+		outputs[w.name.initialName+"."+output.name.initialName] = output
+	}
+	return outputs
+}
+
 // A Call represents one parsed call in a workflow.
 type Call struct {
 	namedNode
-	After  string
+	Target []string // dotted call target split into namespaces and callee
+	After  []string // calls this call is declared to be after
 	Inputs []*valueSpec
 }
 
 func NewCall(start, end int, parent node, name string) *Call {
 	call := new(Call)
-	call.setParent(parent)
 	call.namedNode = *newNamedNode(start, end, name)
+	call.setParent(parent)
 	return call
 }
 
+// callName returns the name a call is referred to in its workflow, which is
+// its alias if given or the callee name otherwise. It's empty for a call
+// without either, like one recovered from a syntax error.
+func (c *Call) callName() string {
+	if c.alias != "" {
+		return c.alias
+	}
+	if len(c.Target) == 0 {
+		return ""
+	}
+	return c.Target[len(c.Target)-1]
+}
+
+// A Scatter represents one parsed scatter block in a workflow.
+type Scatter struct {
+	genNode
+	Variable   string
+	Collection *exprRPN
+	raw        string // source text of the collection
+	Body       []node // declarations, calls, scatters and conditionals
+}
+
+func NewScatter(start, end int, parent node, variable string) *Scatter {
+	scatter := new(Scatter)
+	scatter.genNode = genNode{start: start, end: end}
+	scatter.setParent(parent)
+	scatter.Variable = variable
+	return scatter
+}
+
+// A Conditional represents one parsed if block in a workflow.
+type Conditional struct {
+	genNode
+	Condition *exprRPN
+	raw       string // source text of the condition
+	Body      []node // declarations, calls, scatters and conditionals
+}
+
+func NewConditional(start, end int, parent node) *Conditional {
+	conditional := new(Conditional)
+	conditional.genNode = genNode{start: start, end: end}
+	conditional.setParent(parent)
+	return conditional
+}
+
 // A Task represents one parsed task.
 type Task struct {
 	namedNode
+	bodyStart, bodyEnd int // positions of the opening and closing braces
+	commandStart       int // position of the command keyword
+
 	Inputs        []*valueSpec
 	PrvtDecls     []*valueSpec
 	Outputs       []*valueSpec
-	Command       []string
+	Command       []string      // raw text of command literals and placeholders
+	commandParts  []interface{} // literal string or placeholder *expression
+	CommandStyle  CommandStyle
 	Runtime       []*valueSpec
 	Meta          []*valueSpec
 	ParameterMeta []*valueSpec
@@ -156,7 +324,26 @@ type Task struct {
 
 func NewTask(start, end int, parent node, name string) *Task {
 	task := new(Task)
-	task.setParent(parent)
 	task.namedNode = *newNamedNode(start, end, name)
+	task.setParent(parent)
 	return task
 }
+
+// BodySpan returns the positions of the opening and closing braces of the
+// task body.
+func (t *Task) BodySpan() (start, end int) {
+	return t.bodyStart, t.bodyEnd
+}
+
+// DeclarationsInSourceOrder returns inputs, private declarations and outputs
+// of a task in the order they are written, which keeps private declarations
+// written after the command, or between sections, where the author put them.
+func (t *Task) DeclarationsInSourceOrder() []*valueSpec {
+	decls := append(append(
+		append([]*valueSpec(nil), t.Inputs...), t.PrvtDecls...), t.Outputs...,
+	)
+	sort.SliceStable(decls, func(i, j int) bool {
+		return decls[i].getStart() < decls[j].getStart()
+	})
+	return decls
+}
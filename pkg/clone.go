@@ -0,0 +1,341 @@
+package wdlparser
+
+// A cloner deep-copies nodes. Every node is copied once, so a node referred to
+// from more than one place, like a call in both Calls of its workflow and Body
+// of its scatter, is still referred to from all of them in the copy.
+type cloner struct {
+	clones map[node]node // copies by original nodes
+}
+
+func newCloner() *cloner {
+	return &cloner{map[node]node{}}
+}
+
+// relink makes copies children of copies of their parents. A copy whose parent
+// isn't copied keeps the original parent.
+func (c *cloner) relink() {
+	for original, clone := range c.clones {
+		if parent, ok := c.clones[original.getParent()]; ok {
+			clone.setParent(parent)
+		}
+	}
+}
+
+func (c *cloner) genNode(g genNode) genNode {
+	g.comments = append([]string(nil), g.comments...)
+	return g
+}
+
+func (c *cloner) identifier(id *identifier) *identifier {
+	if id == nil {
+		return nil
+	}
+	clone := *id
+	return &clone
+}
+
+func (c *cloner) namedNode(n namedNode) namedNode {
+	n.genNode = c.genNode(n.genNode)
+	n.name = c.identifier(n.name)
+	return n
+}
+
+// value copies a value, including elements of an array value.
+func (c *cloner) value(v value) value {
+	if elems, ok := v.govalue.([]value); ok {
+		clone := make([]value, 0, len(elems))
+		for _, elem := range elems {
+			clone = append(clone, c.value(elem))
+		}
+		v.govalue = clone
+	}
+	if entries, ok := v.govalue.([]mapEntry); ok {
+		clone := make([]mapEntry, 0, len(entries))
+		for _, e := range entries {
+			clone = append(clone, mapEntry{c.value(e.key), c.value(e.value)})
+		}
+		v.govalue = clone
+	}
+	return v
+}
+
+// meta copies a go value of metadata, including elements of arrays and
+// objects.
+func (c *cloner) meta(m interface{}) interface{} {
+	switch m := m.(type) {
+	case []interface{}:
+		clone := make([]interface{}, 0, len(m))
+		for _, elem := range m {
+			clone = append(clone, c.meta(elem))
+		}
+		return clone
+	case map[string]interface{}:
+		clone := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			clone[k] = c.meta(v)
+		}
+		return clone
+	}
+	return m
+}
+
+func (c *cloner) rpn(e *exprRPN) *exprRPN {
+	if e == nil {
+		return nil
+	}
+	clone := make(exprRPN, 0, len(*e))
+	for _, elem := range *e {
+		switch elem := elem.(type) {
+		case value:
+			clone = append(clone, c.value(elem))
+		case *identifier:
+			clone = append(clone, c.identifier(elem))
+		case *expression:
+			clone = append(clone, c.expression(elem))
+		default: // operators, functions and members are values
+			clone = append(clone, elem)
+		}
+	}
+	return &clone
+}
+
+func (c *cloner) expression(e *expression) *expression {
+	if clone, ok := c.clones[e]; ok {
+		return clone.(*expression)
+	}
+	clone := &expression{genNode: c.genNode(e.genNode)}
+	c.clones[e] = clone
+	clone.rpn = *c.rpn(&e.rpn)
+	for _, sub := range e.subExprs {
+		clone.subExprs.push(c.expression(sub))
+	}
+	if e.options != nil {
+		clone.options = make(map[string]value, len(e.options))
+		for name, v := range e.options {
+			clone.options[name] = c.value(v)
+		}
+	}
+	return clone
+}
+
+func (c *cloner) valueSpec(v *valueSpec) *valueSpec {
+	if clone, ok := c.clones[v]; ok {
+		return clone.(*valueSpec)
+	}
+	clone := new(valueSpec)
+	*clone = *v
+	c.clones[v] = clone
+	clone.genNode = c.genNode(v.genNode)
+	clone.name = c.identifier(v.name)
+	clone.value = c.rpn(v.value)
+	clone.meta = c.meta(v.meta)
+	return clone
+}
+
+func (c *cloner) valueSpecs(vs []*valueSpec) []*valueSpec {
+	if vs == nil {
+		return nil
+	}
+	clone := make([]*valueSpec, 0, len(vs))
+	for _, v := range vs {
+		clone = append(clone, c.valueSpec(v))
+	}
+	return clone
+}
+
+// nodes copies declarations, calls, scatters and conditionals in a workflow
+// body.
+func (c *cloner) nodes(ns []node) []node {
+	if ns == nil {
+		return nil
+	}
+	clone := make([]node, 0, len(ns))
+	for _, n := range ns {
+		switch n := n.(type) {
+		case *valueSpec:
+			clone = append(clone, c.valueSpec(n))
+		case *Call:
+			clone = append(clone, c.call(n))
+		case *Scatter:
+			clone = append(clone, c.scatter(n))
+		case *Conditional:
+			clone = append(clone, c.conditional(n))
+		}
+	}
+	return clone
+}
+
+func (c *cloner) call(call *Call) *Call {
+	if clone, ok := c.clones[call]; ok {
+		return clone.(*Call)
+	}
+	clone := new(Call)
+	*clone = *call
+	c.clones[call] = clone
+	clone.namedNode = c.namedNode(call.namedNode)
+	clone.Target = append([]string(nil), call.Target...)
+	clone.After = append([]string(nil), call.After...)
+	clone.Inputs = c.valueSpecs(call.Inputs)
+	return clone
+}
+
+func (c *cloner) scatter(s *Scatter) *Scatter {
+	if clone, ok := c.clones[s]; ok {
+		return clone.(*Scatter)
+	}
+	clone := new(Scatter)
+	*clone = *s
+	c.clones[s] = clone
+	clone.genNode = c.genNode(s.genNode)
+	clone.Collection = c.rpn(s.Collection)
+	clone.Body = c.nodes(s.Body)
+	return clone
+}
+
+func (c *cloner) conditional(cond *Conditional) *Conditional {
+	if clone, ok := c.clones[cond]; ok {
+		return clone.(*Conditional)
+	}
+	clone := new(Conditional)
+	*clone = *cond
+	c.clones[cond] = clone
+	clone.genNode = c.genNode(cond.genNode)
+	clone.Condition = c.rpn(cond.Condition)
+	clone.Body = c.nodes(cond.Body)
+	return clone
+}
+
+func (c *cloner) workflow(w *Workflow) *Workflow {
+	if w == nil {
+		return nil
+	}
+	clone := new(Workflow)
+	*clone = *w
+	c.clones[w] = clone
+	clone.namedNode = c.namedNode(w.namedNode)
+	clone.Inputs = c.valueSpecs(w.Inputs)
+	clone.PrvtDecls = c.valueSpecs(w.PrvtDecls)
+	clone.Outputs = c.valueSpecs(w.Outputs)
+	if w.Calls != nil {
+		clone.Calls = make([]*Call, 0, len(w.Calls))
+		for _, call := range w.Calls {
+			clone.Calls = append(clone.Calls, c.call(call))
+		}
+	}
+	clone.Blocks = c.nodes(w.Blocks)
+	clone.Meta = c.valueSpecs(w.Meta)
+	clone.ParameterMeta = c.valueSpecs(w.ParameterMeta)
+	return clone
+}
+
+func (c *cloner) task(t *Task) *Task {
+	clone := new(Task)
+	*clone = *t
+	c.clones[t] = clone
+	clone.namedNode = c.namedNode(t.namedNode)
+	clone.Inputs = c.valueSpecs(t.Inputs)
+	clone.PrvtDecls = c.valueSpecs(t.PrvtDecls)
+	clone.Outputs = c.valueSpecs(t.Outputs)
+	clone.Command = append([]string(nil), t.Command...)
+	if t.commandParts != nil {
+		clone.commandParts = make([]interface{}, 0, len(t.commandParts))
+		for _, part := range t.commandParts {
+			if e, ok := part.(*expression); ok {
+				part = c.expression(e)
+			}
+			clone.commandParts = append(clone.commandParts, part)
+		}
+	}
+	clone.Runtime = c.valueSpecs(t.Runtime)
+	clone.Meta = c.valueSpecs(t.Meta)
+	clone.ParameterMeta = c.valueSpecs(t.ParameterMeta)
+	return clone
+}
+
+func (c *cloner) importSpec(is *importSpec) *importSpec {
+	clone := new(importSpec)
+	*clone = *is
+	c.clones[is] = clone
+	clone.namedNode = c.namedNode(is.namedNode)
+	clone.uri = c.rpn(is.uri)
+	clone.importAliases = make(map[string]string, len(is.importAliases))
+	for original, alias := range is.importAliases {
+		clone.importAliases[original] = alias
+	}
+	return clone
+}
+
+func (c *cloner) structNode(s *Struct) *Struct {
+	clone := new(Struct)
+	*clone = *s
+	c.clones[s] = clone
+	clone.namedNode = c.namedNode(s.namedNode)
+	clone.Members = c.valueSpecs(s.Members)
+	return clone
+}
+
+func (c *cloner) wdl(w *WDL) *WDL {
+	clone := new(WDL)
+	*clone = *w
+	c.clones[w] = clone
+	clone.namedNode = c.namedNode(w.namedNode)
+	if w.Imports != nil {
+		clone.Imports = make([]*importSpec, 0, len(w.Imports))
+		for _, is := range w.Imports {
+			clone.Imports = append(clone.Imports, c.importSpec(is))
+		}
+	}
+	clone.Workflow = c.workflow(w.Workflow)
+	if w.Tasks != nil {
+		clone.Tasks = make([]*Task, 0, len(w.Tasks))
+		for _, t := range w.Tasks {
+			clone.Tasks = append(clone.Tasks, c.task(t))
+		}
+	}
+	if w.Structs != nil {
+		clone.Structs = make([]*Struct, 0, len(w.Structs))
+		for _, st := range w.Structs {
+			clone.Structs = append(clone.Structs, c.structNode(st))
+		}
+	}
+	clone.Warnings = append([]Diagnostic(nil), w.Warnings...)
+	return clone
+}
+
+// Clone returns a deep copy of a document, which can be changed without
+// changing the original. Nodes of the copy keep their IDs and are children of
+// copies of their parents, while imported documents are shared with the
+// original.
+func (w *WDL) Clone() *WDL {
+	cl := newCloner()
+	clone := cl.wdl(w)
+	cl.relink()
+	return clone
+}
+
+// Clone returns a deep copy of a workflow, which is still a child of the
+// document of the original.
+func (w *Workflow) Clone() *Workflow {
+	cl := newCloner()
+	clone := cl.workflow(w)
+	cl.relink()
+	return clone
+}
+
+// Clone returns a deep copy of a task, which is still a child of the document
+// of the original.
+func (t *Task) Clone() *Task {
+	cl := newCloner()
+	clone := cl.task(t)
+	cl.relink()
+	return clone
+}
+
+// Clone returns a deep copy of a call, which is still a child of the workflow,
+// scatter or conditional of the original.
+func (c *Call) Clone() *Call {
+	cl := newCloner()
+	clone := cl.call(c)
+	cl.relink()
+	return clone
+}
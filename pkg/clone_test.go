@@ -0,0 +1,62 @@
+package wdlparser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClone(t *testing.T) {
+	inputPath := "testdata/workflow_scatter_gather.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	before, _ := Format(result)
+
+	clone := result.Clone()
+	formatted, _ := Format(clone)
+	if diff := cmp.Diff(before, formatted); diff != "" {
+		t.Errorf("clone differs from the original:\n%s", diff)
+	}
+
+	originals := map[node]bool{}
+	walk(result, func(n node) bool {
+		originals[n] = true
+		return true
+	})
+	walk(clone, func(n node) bool {
+		if originals[n] {
+			t.Errorf("node %T at %d is shared", n, n.getStart())
+		}
+		if documentOf(n) != clone {
+			t.Errorf("node %T at %d isn't in the clone", n, n.getStart())
+		}
+		return true
+	})
+	scatter := clone.Workflow.Blocks[0].(*Scatter)
+	if scatter.Body[1] != clone.Workflow.Calls[0] {
+		t.Errorf("call in scatter should be the call of the workflow")
+	}
+
+	// Changes to the clone leave the original unchanged
+	(*clone.Workflow.Outputs[1].value)[0] = newIdentifier("changed", true)
+	clone.Tasks[0].Inputs[0].name.initialName = "changed"
+	(*scatter.Collection)[0].(*identifier).initialName = "changed"
+	if after, _ := Format(result); after != before {
+		t.Errorf("original changed with its clone:\n%s", after)
+	}
+	output := (*result.Workflow.Outputs[1].value)[0].(*identifier)
+	collection := (*result.Workflow.Blocks[0].(*Scatter).Collection)[0]
+	if output.initialName != "greeting_name" ||
+		collection.(*identifier).initialName != "names" {
+		t.Errorf("expressions of the original changed with its clone")
+	}
+
+	task := result.Tasks[0].Clone()
+	if task == result.Tasks[0] || task.getParent() != result {
+		t.Errorf("cloned task should be a copy in the original document")
+	}
+}
@@ -0,0 +1,231 @@
+package wdlparser
+
+import (
+	"regexp"
+	"strings"
+
+	parser "github.com/yunhailuo/wdlparser/pkg/antlr4_grammar/1_1"
+)
+
+// A CommandStyle tells which delimiters enclose a task command. Both styles
+// accept ~{} placeholders but only the brace style also accepts ${}, so a
+// ${} in a heredoc command is kept as literal shell syntax.
+type CommandStyle int
+
+const (
+	_              CommandStyle = iota // leave 0 for a task without command
+	BraceCommand                       // command { ... }
+	HereDocCommand                     // command <<< ... >>>
+)
+
+// CommandParts returns the command of a task as a sequence of literal strings
+// and placeholder expressions, in source order. Literal strings are never
+// empty, so back-to-back placeholders like ~{a}~{b} are adjacent parts.
+//
+// CRLF line endings of the command are normalized to LF, in both the parts
+// and the raw Command, so a command written on Windows runs the same in a
+// shell. Positions of nodes are still offsets in the source as written.
+func (t *Task) CommandParts() []interface{} {
+	return t.commandParts
+}
+
+// CommandString reproduces the raw command of a task, placeholders included.
+func (t *Task) CommandString() string {
+	return strings.Join(t.Command, "")
+}
+
+// NormalizedCommand returns the command of a task with whitespace stripped
+// as WDL engines do before running it: a first or last line holding only
+// whitespace is removed, and so is the leading whitespace common to all lines
+// but those holding only whitespace. Placeholders are kept as written, like in
+// CommandString, which keeps the raw command.
+func (t *Task) NormalizedCommand() string {
+	lines := strings.Split(t.CommandString(), "\n")
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if common < 0 || n < common {
+			common = n
+		}
+	}
+	for i, line := range lines {
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if n > common {
+			n = common
+		}
+		if n > 0 {
+			lines[i] = line[n:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HasStdoutOutput reports whether any output of a task is computed with the
+// standard output or error of its command, through stdout() or stderr().
+func (t *Task) HasStdoutOutput() bool {
+	for _, output := range t.Outputs {
+		if output.value.callsFunction("stdout", "stderr") {
+			return true
+		}
+	}
+	return false
+}
+
+// CommandUsesStrictMode reports whether the command of a task turns on bash
+// strict mode, with set -euo pipefail or the same options given separately,
+// like set -eu -o pipefail or set -o errexit -o nounset -o pipefail. Options
+// set inside placeholders are not seen.
+func (t *Task) CommandUsesStrictMode() bool {
+	var literal strings.Builder
+	for _, part := range t.commandParts {
+		if s, ok := part.(string); ok {
+			literal.WriteString(s)
+		} else {
+			literal.WriteString(" ~{} ")
+		}
+	}
+	statements := strings.FieldsFunc(literal.String(), func(r rune) bool {
+		return r == '\n' || r == ';' || r == '&' || r == '|'
+	})
+	for _, statement := range statements {
+		args := strings.Fields(statement)
+		if len(args) == 0 || args[0] != "set" {
+			continue
+		}
+		errexit, nounset, pipefail := false, false, false
+		for i, arg := range args[1:] {
+			if !strings.HasPrefix(arg, "-") {
+				continue
+			}
+			errexit = errexit || strings.Contains(arg, "e")
+			nounset = nounset || strings.Contains(arg, "u")
+			if !strings.HasSuffix(arg, "o") || i+2 >= len(args) {
+				continue
+			}
+			switch args[i+2] {
+			case "errexit":
+				errexit = true
+			case "nounset":
+				nounset = true
+			case "pipefail":
+				pipefail = true
+			}
+		}
+		if errexit && nounset && pipefail {
+			return true
+		}
+	}
+	return false
+}
+
+var envRefPattern = regexp.MustCompile(
+	`\$(?:\{([A-Za-z_][A-Za-z0-9_]*)\}|([A-Za-z_][A-Za-z0-9_]*))`,
+)
+
+// CommandEnvRefs returns names of shell variables, like HOME in $HOME or
+// ${HOME}, which the command of a task refers to outside of placeholders, once
+// each and in order of first reference. Variables the command sets itself are
+// included since they can't be told apart from the environment ones.
+func (t *Task) CommandEnvRefs() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, part := range t.commandParts {
+		s, ok := part.(string)
+		if !ok {
+			continue
+		}
+		for _, m := range envRefPattern.FindAllStringSubmatch(s, -1) {
+			name := m[1] + m[2]
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// CommandReferences returns identifiers which placeholders in the command of
+// a task refer to, like reads in ~{reads} or ~{sep=" " reads}, once for each
+// name and in order of first reference. These are the declarations to be
+// evaluated, and files among them localized, before the command runs.
+func (t *Task) CommandReferences() []*identifier {
+	var refs []*identifier
+	seen := map[string]bool{}
+	for _, part := range t.commandParts {
+		e, ok := part.(*expression)
+		if !ok {
+			continue
+		}
+		for _, ref := range e.rpn.References() {
+			if !seen[ref.initialName] {
+				seen[ref.initialName] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// Antlr4 listeners
+
+func (l *wdlv1_1Listener) EnterTask_command(ctx *parser.Task_commandContext) {
+	l.astContext.taskNode.commandStart = ctx.GetStart().GetStart()
+	if ctx.BeginHereDoc() != nil {
+		l.astContext.taskNode.CommandStyle = HereDocCommand
+	} else {
+		l.astContext.taskNode.CommandStyle = BraceCommand
+	}
+}
+
+func (l *wdlv1_1Listener) ExitTask_command_string_part(
+	ctx *parser.Task_command_string_partContext,
+) {
+	literal := strings.ReplaceAll(ctx.GetText(), "\r\n", "\n")
+	if literal == "" {
+		return
+	}
+	l.astContext.taskNode.Command = append(
+		l.astContext.taskNode.Command, literal,
+	)
+	l.astContext.taskNode.commandParts = append(
+		l.astContext.taskNode.commandParts, literal,
+	)
+}
+
+func (l *wdlv1_1Listener) EnterTask_command_expr_part(
+	ctx *parser.Task_command_expr_partContext,
+) {
+	l.astContext.exprNode = newExpression(
+		ctx.GetStart().GetStart(),
+		ctx.GetStop().GetStop(),
+	)
+}
+
+func (l *wdlv1_1Listener) ExitTask_command_expr_part(
+	ctx *parser.Task_command_expr_partContext,
+) {
+	l.warnDollarPlaceholder(ctx.StringCommandStart(), ctx)
+	e := l.popPlaceholder(ctx.Expr(), ctx.AllExpression_placeholder_option())
+	l.astContext.exprNode = nil
+	l.astContext.taskNode.Command = append(
+		l.astContext.taskNode.Command,
+		strings.ReplaceAll(sourceText(ctx), "\r\n", "\n"),
+	)
+	if e == nil {
+		return
+	}
+	e.setParent(l.astContext.taskNode)
+	l.astContext.taskNode.commandParts = append(
+		l.astContext.taskNode.commandParts, e,
+	)
+}
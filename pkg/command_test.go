@@ -0,0 +1,379 @@
+package wdlparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTaskCommandParts(t *testing.T) {
+	inputPath := "testdata/task_command_placeholder.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	expectedParts := []interface{}{
+		"\n        echo \"Hello ",
+		&expression{
+			genNode: genNode{start: 117, end: 121},
+			rpn:     exprRPN{newIdentifier("world", true)},
+		},
+		"\"\n    ",
+	}
+	task := result.Tasks[0]
+	if diff := cmp.Diff(
+		expectedParts, task.CommandParts(), commonCmpopts...,
+	); diff != "" {
+		t.Errorf("unexpected task command parts:\n%s", diff)
+	}
+
+	expectedCommand := "\n        echo \"Hello ~{ world }\"\n    "
+	if diff := cmp.Diff(expectedCommand, task.CommandString()); diff != "" {
+		t.Errorf("unexpected task command string:\n%s", diff)
+	}
+}
+
+func TestTaskCommandStyle(t *testing.T) {
+	testCases := []struct {
+		wdl   string
+		style CommandStyle
+		parts []interface{}
+	}{
+		{
+			"version 1.1 task Test {command { echo ${x} ~{y} }}",
+			BraceCommand,
+			[]interface{}{
+				" echo ",
+				&expression{
+					genNode: genNode{start: 40, end: 40},
+					rpn:     exprRPN{newIdentifier("x", true)},
+				},
+				" ",
+				&expression{
+					genNode: genNode{start: 45, end: 45},
+					rpn:     exprRPN{newIdentifier("y", true)},
+				},
+				" ",
+			},
+		},
+		{
+			"version 1.1 task Test {command <<< echo ${x} ~{y} >>>}",
+			HereDocCommand,
+			[]interface{}{
+				" echo ${x} ",
+				&expression{
+					genNode: genNode{start: 47, end: 47},
+					rpn:     exprRPN{newIdentifier("y", true)},
+				},
+				" ",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		result, err := Antlr4Parse(tc.wdl)
+		if err != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(err), tc.wdl,
+			)
+		}
+		task := result.Tasks[0]
+		if task.CommandStyle != tc.style {
+			t.Errorf(
+				"unexpected command style %v, expect %v",
+				task.CommandStyle, tc.style,
+			)
+		}
+		if diff := cmp.Diff(
+			tc.parts, task.CommandParts(), commonCmpopts...,
+		); diff != "" {
+			t.Errorf("unexpected task command parts:\n%s", diff)
+		}
+	}
+}
+
+func TestPlaceholderOption(t *testing.T) {
+	testCases := []struct {
+		wdl     string
+		options map[string]string
+	}{
+		{
+			`version 1.1 task Test {command <<< ~{sep=", " arr} >>>}`,
+			map[string]string{"sep": ", "},
+		},
+		{
+			`version 1.1 task Test {command <<< ~{true="--yes" false="" f} >>>}`,
+			map[string]string{"true": "--yes", "false": ""},
+		},
+		{
+			`version 1.1 task Test {command <<< ~{default=1 i} >>>}`,
+			map[string]string{"default": "1"},
+		},
+	}
+	for _, tc := range testCases {
+		result, err := Antlr4Parse(tc.wdl)
+		if err != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(err), tc.wdl,
+			)
+		}
+		placeholder := result.Tasks[0].CommandParts()[1].(*expression)
+		if len(placeholder.rpn) != 1 {
+			t.Errorf(
+				"unexpected placeholder expression %v in %q",
+				placeholder.rpn, tc.wdl,
+			)
+		}
+		for name, want := range tc.options {
+			got, ok := placeholder.Option(name)
+			if !ok || got != want {
+				t.Errorf(
+					"unexpected placeholder option %q in %q: got %q, expect %q",
+					name, tc.wdl, got, want,
+				)
+			}
+		}
+	}
+}
+
+func TestTaskCommandAdjacentPlaceholders(t *testing.T) {
+	wdl := "version 1.1 task Test {command <<<~{a}~{b}>>>}"
+	result, err := Antlr4Parse(wdl)
+	if err != nil {
+		t.Errorf("Found %d errors in %q, expect no errors", len(err), wdl)
+	}
+
+	task := result.Tasks[0]
+	expectedParts := []interface{}{
+		&expression{
+			genNode: genNode{start: 36, end: 36},
+			rpn:     exprRPN{newIdentifier("a", true)},
+		},
+		&expression{
+			genNode: genNode{start: 40, end: 40},
+			rpn:     exprRPN{newIdentifier("b", true)},
+		},
+	}
+	if diff := cmp.Diff(
+		expectedParts, task.CommandParts(), commonCmpopts...,
+	); diff != "" {
+		t.Errorf("unexpected task command parts:\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"~{a}", "~{b}"}, task.Command); diff != "" {
+		t.Errorf("unexpected task command:\n%s", diff)
+	}
+	if diff := cmp.Diff("~{a}~{b}", task.CommandString()); diff != "" {
+		t.Errorf("unexpected task command string:\n%s", diff)
+	}
+}
+
+func TestHasStdoutOutput(t *testing.T) {
+	testCases := []struct {
+		wdl  string
+		want bool
+	}{
+		{"testdata/task_output.wdl", true},
+		{"testdata/task_command.wdl", false},
+	}
+	for _, tc := range testCases {
+		result, err := Antlr4Parse(tc.wdl)
+		if err != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(err), tc.wdl,
+			)
+		}
+		if got := result.Tasks[0].HasStdoutOutput(); got != tc.want {
+			t.Errorf(
+				"HasStdoutOutput of %q is %v, expect %v", tc.wdl, got, tc.want,
+			)
+		}
+	}
+}
+
+func TestCommandUsesStrictMode(t *testing.T) {
+	testCases := []struct {
+		command string
+		want    bool
+	}{
+		{"set -euo pipefail\n        echo hi", true},
+		{"set -eu -o pipefail; echo hi", true},
+		{"set -o errexit -o nounset -o pipefail", true},
+		{"set -e\n        echo hi | wc", false},
+		{"echo set -euo pipefail", false},
+		{"echo hi", false},
+	}
+	for _, tc := range testCases {
+		input := "version 1.1 task T { command <<<\n        " + tc.command +
+			"\n    >>> }"
+		result, err := Antlr4Parse(input)
+		if err != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(err), input,
+			)
+			continue
+		}
+		if got := result.Tasks[0].CommandUsesStrictMode(); got != tc.want {
+			t.Errorf(
+				"CommandUsesStrictMode of %q is %v, expect %v",
+				tc.command, got, tc.want,
+			)
+		}
+	}
+}
+
+func TestCommandEnvRefs(t *testing.T) {
+	testCases := []struct {
+		wdl  string
+		want []string
+	}{
+		{
+			`version 1.1 task T {
+    input {
+        String x
+    }
+    command <<<
+        cd $HOME && echo ~{x} ${TMPDIR} $HOME
+    >>>
+}`,
+			[]string{"HOME", "TMPDIR"},
+		},
+		{
+			"version 1.1 task T { command { echo ${x} ~{y} } }",
+			nil,
+		},
+	}
+	for _, tc := range testCases {
+		result, err := Antlr4Parse(tc.wdl)
+		if err != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(err), tc.wdl,
+			)
+			continue
+		}
+		got := result.Tasks[0].CommandEnvRefs()
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("unexpected env refs of %q:\n%s", tc.wdl, diff)
+		}
+	}
+}
+
+func TestCommandReferences(t *testing.T) {
+	testCases := []struct {
+		wdl  string
+		want []string
+	}{
+		{
+			`version 1.1 task T {
+    input {
+        Array[File] reads
+        Pair[Int, String] p
+        Int threads = 1
+    }
+    command <<<
+        tool -t ~{threads} ~{sep=" " reads} ~{p.right} $HOME
+        echo ~{threads + 1} ~{"x"}
+    >>>
+}`,
+			[]string{"threads", "reads", "p"},
+		},
+		{
+			"version 1.1 task T { command { echo ${x} ~{y} } }",
+			[]string{"x", "y"},
+		},
+		{"version 1.1 task T { command <<< echo ${x} >>> }", nil},
+	}
+	for _, tc := range testCases {
+		result, err := Antlr4Parse(tc.wdl)
+		if err != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(err), tc.wdl,
+			)
+			continue
+		}
+		var got []string
+		for _, ref := range result.Tasks[0].CommandReferences() {
+			got = append(got, ref.initialName)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("unexpected command references of %q:\n%s", tc.wdl, diff)
+		}
+	}
+}
+
+func TestNormalizedCommand(t *testing.T) {
+	testCases := []struct {
+		command string
+		want    string
+	}{
+		{
+			"<<<\n        echo \"Hello world\"\n    >>>",
+			`echo "Hello world"`,
+		},
+		{
+			"<<<\n        if true; then\n\n            echo ~{x}\n" +
+				"        fi\n    >>>",
+			"if true; then\n\n    echo ~{x}\nfi",
+		},
+		{
+			"{ echo one\n    echo two }",
+			"echo one\n   echo two ",
+		},
+	}
+	for _, tc := range testCases {
+		wdl := "version 1.1 task T { input { Int x } command " +
+			tc.command + " }"
+		result, err := Antlr4Parse(wdl)
+		if err != nil {
+			t.Errorf("Found %d errors in %q, expect no errors", len(err), wdl)
+			continue
+		}
+		got := result.Tasks[0].NormalizedCommand()
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("unexpected normalized command of %q:\n%s", wdl, diff)
+		}
+	}
+}
+
+func TestCRLFCommand(t *testing.T) {
+	input := strings.Join([]string{
+		"version 1.1",
+		"task T {",
+		"    input {",
+		`        String s = "a b"`,
+		"    }",
+		"    command <<<",
+		"        echo ~{s}",
+		"        ls",
+		"    >>>",
+		"}",
+	}, "\r\n")
+	result, err := Antlr4Parse(input)
+	if err != nil {
+		t.Errorf("Found %d errors, expect no errors", len(err))
+	}
+	task := result.Tasks[0]
+	expected := "\n        echo ~{s}\n        ls\n    "
+	if diff := cmp.Diff(expected, task.CommandString()); diff != "" {
+		t.Errorf("unexpected task command string:\n%s", diff)
+	}
+	for _, part := range task.CommandParts() {
+		if s, ok := part.(string); ok && strings.Contains(s, "\r") {
+			t.Errorf("command part %q has a carriage return", s)
+		}
+	}
+
+	// Positions count carriage returns in the source as written
+	decl := task.Inputs[0]
+	if decl.getStart() != 44 || decl.getEnd() != 59 {
+		t.Errorf(
+			"input is at [%d, %d], expect [44, 59]",
+			decl.getStart(), decl.getEnd(),
+		)
+	}
+	if src := result.Source(decl); src != `String s = "a b"` {
+		t.Errorf("unexpected source of input: %q", src)
+	}
+}
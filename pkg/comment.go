@@ -0,0 +1,50 @@
+package wdlparser
+
+import (
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+	parser "github.com/yunhailuo/wdlparser/pkg/antlr4_grammar/1_1"
+)
+
+// LeadingComments returns the comments, as written, on lines right before a
+// node.
+func (v *genNode) LeadingComments() []string { return v.comments }
+
+func (v *genNode) addComment(comment string) {
+	v.comments = append(v.comments, comment)
+}
+
+// attachComments attaches every comment to the node starting at the first
+// token following the comment. A comment sharing its line with a preceding
+// token trails that token rather than leading the next node, so it's ignored.
+func attachComments(w *WDL, stream *antlr.CommonTokenStream) {
+	nodes := map[int]node{}
+	walk(w, func(n node) bool {
+		if _, ok := n.(*WDL); !ok {
+			nodes[n.getStart()] = n
+		}
+		return true
+	})
+
+	tokens := stream.GetAllTokens()
+	prevLine := 0 // line of the previous token on the default channel
+	for i, token := range tokens {
+		switch token.GetChannel() {
+		case antlr.TokenDefaultChannel:
+			prevLine = token.GetLine()
+			continue
+		case parser.WdlV1_1LexerCOMMENTS:
+		default:
+			continue
+		}
+		if token.GetLine() == prevLine {
+			continue
+		}
+		next := stream.NextTokenOnChannel(i, antlr.TokenDefaultChannel)
+		if next < 0 {
+			continue
+		}
+		if n, ok := nodes[tokens[next].GetStart()]; ok {
+			n.addComment(token.GetText())
+		}
+	}
+}
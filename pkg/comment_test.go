@@ -0,0 +1,37 @@
+package wdlparser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLeadingComments(t *testing.T) {
+	inputPath := "testdata/comment.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	testCases := []struct {
+		n    node
+		want []string
+	}{
+		{
+			result.Workflow,
+			[]string{"# Reusable read processing", "# tasks and workflow"},
+		},
+		{result.Workflow.Inputs[0], []string{"# Sample name"}},
+		{result.Workflow.Inputs[1], nil},
+		{result.Workflow.Calls[0], []string{"# Align reads"}},
+		{result.Tasks[0], nil},
+		{result.Tasks[0].Meta[0], []string{"# Task author"}},
+	}
+	for _, tc := range testCases {
+		if diff := cmp.Diff(tc.want, tc.n.LeadingComments()); diff != "" {
+			t.Errorf("unexpected leading comments:\n%s", diff)
+		}
+	}
+}
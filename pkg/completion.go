@@ -0,0 +1,80 @@
+package wdlparser
+
+import "strings"
+
+// Kinds of completions
+const (
+	InputCompletion       = "input"
+	DeclarationCompletion = "declaration"
+	ScatterCompletion     = "scatter variable"
+	CallCompletion        = "call"
+	NamespaceCompletion   = "namespace"
+)
+
+// A Completion is a name visible at a source offset, for editors to offer
+// while typing. Type is the WDL type of an input, declaration or scatter
+// variable as seen at the offset, or the callee of a call.
+type Completion struct {
+	Name string
+	Kind string
+	Type string
+}
+
+// typeAt returns the type of a declaration as seen from a node, like an
+// array for a declaration in a scatter seen from outside of the scatter.
+// Types not modeled, like structs, are returned as written.
+func typeAt(decl *valueSpec, from node) string {
+	if t := gathered(parseType(decl.typ), decl, from); t != nil {
+		return t.typeString()
+	}
+	return decl.typ
+}
+
+// CompletionsAt returns names visible at a 0-based source offset: inputs and
+// declarations of the enclosing workflow or task, variables of enclosing
+// scatters, calls of the enclosing workflow and namespaces of imports.
+func (w *WDL) CompletionsAt(offset int) []Completion {
+	from := w.nodeAt(offset)
+	if from == nil {
+		return nil
+	}
+	var completions []Completion
+	declarations := func(kind string, decls []*valueSpec) {
+		for _, decl := range decls {
+			completions = append(completions, Completion{
+				decl.name.initialName, kind, typeAt(decl, from),
+			})
+		}
+	}
+	switch container := enclosing(from).(type) {
+	case *Task:
+		declarations(InputCompletion, container.Inputs)
+		declarations(DeclarationCompletion, container.PrvtDecls)
+	case *Workflow:
+		declarations(InputCompletion, container.Inputs)
+		declarations(DeclarationCompletion, container.PrvtDecls)
+		for n := from; n != nil && n != container; n = n.getParent() {
+			if s, ok := n.(*Scatter); ok {
+				t := ""
+				collection, _ := s.Collection.inferType(nil, w.scope(s), false)
+				if a, ok := collection.(array); ok {
+					t = a.elem.typeString()
+				}
+				completions = append(
+					completions, Completion{s.Variable, ScatterCompletion, t},
+				)
+			}
+		}
+		for _, c := range container.Calls {
+			completions = append(completions, Completion{
+				c.callName(), CallCompletion, strings.Join(c.Target, "."),
+			})
+		}
+	}
+	for _, is := range w.Imports {
+		completions = append(
+			completions, Completion{is.namespace(), NamespaceCompletion, ""},
+		)
+	}
+	return completions
+}
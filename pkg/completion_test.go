@@ -0,0 +1,59 @@
+package wdlparser
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompletionsAt(t *testing.T) {
+	testCases := []struct {
+		path  string
+		after string // completions are requested right after this text
+		want  []Completion
+	}{
+		{
+			"testdata/task_command_placeholder.wdl",
+			"echo ",
+			[]Completion{{"world", InputCompletion, "String"}},
+		},
+		{
+			"testdata/workflow_scatter_gather.wdl",
+			"input: name = ",
+			[]Completion{
+				{"names", InputCompletion, "Array[String]"},
+				{"greeting_name", DeclarationCompletion, "String"},
+				{"name", ScatterCompletion, "String"},
+				{"Greet", CallCompletion, "Greet"},
+			},
+		},
+		{
+			"testdata/workflow_scatter_gather.wdl",
+			"Array[String] greeted = ",
+			[]Completion{
+				{"names", InputCompletion, "Array[String]"},
+				{"greeting_name", DeclarationCompletion, "Array[String]"},
+				{"Greet", CallCompletion, "Greet"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		result, errs := Antlr4Parse(tc.path)
+		if errs != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(errs), tc.path,
+			)
+		}
+		src, err := os.ReadFile(tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		offset := strings.Index(string(src), tc.after) + len(tc.after)
+		got := result.CompletionsAt(offset)
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("unexpected completions after %q:\n%s", tc.after, diff)
+		}
+	}
+}
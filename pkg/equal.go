@@ -0,0 +1,54 @@
+package wdlparser
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// equalOptions returns options comparing documents by their content. NodeIDs,
+// parents, paths of documents and documents resolved for imports are never
+// compared. If ignorePositions is true, neither are positions of nodes nor
+// source text of values, so that a document compares equal to its formatted
+// version.
+func equalOptions(ignorePositions bool) cmp.Options {
+	opts := cmp.Options{
+		cmp.AllowUnexported(
+			genNode{}, identifier{}, namedNode{}, valueSpec{}, WDL{},
+			importSpec{}, Struct{}, Workflow{}, Call{}, Scatter{},
+			Conditional{}, Task{}, expression{}, value{}, array{},
+			mapping{}, optional{}, nAryOp{}, function{},
+		),
+		cmpopts.IgnoreFields(genNode{}, "id", "parent"),
+		cmpopts.IgnoreFields(WDL{}, "namedNode", "Path", "source"),
+		cmpopts.IgnoreFields(importSpec{}, "wdl"),
+	}
+	if ignorePositions {
+		opts = append(
+			opts,
+			cmpopts.IgnoreFields(genNode{}, "start", "end"),
+			cmpopts.IgnoreFields(Workflow{}, "bodyStart", "bodyEnd"),
+			cmpopts.IgnoreFields(
+				Task{}, "bodyStart", "bodyEnd", "commandStart",
+			),
+			cmpopts.IgnoreFields(valueSpec{}, "raw"),
+			cmpopts.IgnoreFields(Scatter{}, "raw"),
+			cmpopts.IgnoreFields(Conditional{}, "raw"),
+			cmpopts.IgnoreFields(Diagnostic{}, "Start", "End"),
+		)
+	}
+	return opts
+}
+
+// Equal reports whether two parsed documents have the same content, like a
+// document and the document parsed from its formatted source, if positions
+// are ignored. NodeIDs and paths of documents are never compared, nor are
+// documents resolved for imports.
+func (w *WDL) Equal(other *WDL, ignorePositions bool) bool {
+	return cmp.Equal(w, other, equalOptions(ignorePositions))
+}
+
+// Diff returns a human-readable report of differences between two parsed
+// documents, compared like Equal, or an empty string if they are equal.
+func (w *WDL) Diff(other *WDL, ignorePositions bool) string {
+	return cmp.Diff(w, other, equalOptions(ignorePositions))
+}
@@ -0,0 +1,55 @@
+package wdlparser
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestEqualFormatted(t *testing.T) {
+	inputPaths, err := filepath.Glob("testdata/*.wdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, inputPath := range inputPaths {
+		result, errs := Antlr4Parse(inputPath)
+		if errs != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(errs), inputPath,
+			)
+		}
+		formatted, err := Format(result)
+		if err != nil {
+			t.Fatalf("failed to format %q: %v", inputPath, err)
+		}
+		reparsed, _ := Antlr4Parse(formatted)
+		if diff := result.Diff(reparsed, true); diff != "" {
+			t.Errorf("formatting %q changes its content:\n%s", inputPath, diff)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	input := `version 1.1
+workflow W {
+    Int x = 1
+}`
+	result, errs := Antlr4Parse(input)
+	if errs != nil {
+		t.Errorf("Found %d errors, expect no errors", len(errs))
+	}
+	same, _ := Antlr4Parse(input)
+	if !result.Equal(same, false) {
+		t.Errorf("documents parsed from the same source should be equal")
+	}
+	moved, _ := Antlr4Parse("version 1.1\n\n" + input[len("version 1.1\n"):])
+	if result.Equal(moved, false) || !result.Equal(moved, true) {
+		t.Errorf("documents should only differ in positions")
+	}
+	changed, errs := Antlr4Parse(input[:len(input)-3] + "2\n}")
+	if errs != nil {
+		t.Errorf("Found %d errors, expect no errors", len(errs))
+	}
+	if result.Equal(changed, true) {
+		t.Errorf("documents with different values should not be equal")
+	}
+}
@@ -2,32 +2,287 @@ package wdlparser
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/antlr/antlr4/runtime/Go/antlr"
+	parser "github.com/yunhailuo/wdlparser/pkg/antlr4_grammar/1_1"
 )
 
-// wdlSyntaxError is used to store WDL error line, column and details of a
-// syntax error
-type wdlSyntaxError struct {
-	line, column int
-	msg          string
+// A Severity tells how serious a SyntaxError or a Diagnostic is.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Codes of syntax errors, one for each kind of them. Unlike messages, which
+// may be reworded, codes are stable and can be used to filter errors or to
+// link them to documentation.
+const (
+	CodeSyntax            = "WDL001" // reported by the ANTLR lexer or parser
+	CodeNonASCII          = "WDL002" // non-ASCII letter in an identifier
+	CodeCast              = "WDL003" // as used like a cast
+	CodeDuplicateWorkflow = "WDL004" // second workflow of a document
+	CodeDeprecated        = "WDL005" // deprecated construct, WarningsAsErrors
+	CodeUnreadable        = "WDL006" // document or directory can't be read
+	CodeInvalidEdit       = "WDL007" // Reparse edit out of the source
+	CodeReservedKeyword   = "WDL008" // keyword used as a name
+	CodeVersion           = "WDL009" // missing or misplaced version statement
+	CodeIncomplete        = "WDL010" // document not built past a syntax error
+	CodeUnformatted       = "WDL011" // document not formatted or written back
+	CodeLiteral           = "WDL012" // literal out of the range of its type
+	CodeMisplacedMeta     = "WDL013" // metadata out of a workflow or task
+)
+
+// A SyntaxError is used to store WDL error line, column and details of a
+// syntax error. EndLine and EndColumn are right after the offending token, or
+// the same as Line and Column if the error isn't on a token. Token is the
+// source text of the offending token, which is empty at the end of the
+// document, and Stop is the 0-based offset of its last character, counted in
+// characters like positions of nodes. Both are zero values for an error which
+// isn't on the source, like a document failing to be read.
+type SyntaxError struct {
+	Line      int      `json:"line"`
+	Column    int      `json:"column"`
+	EndLine   int      `json:"endLine"`
+	EndColumn int      `json:"endColumn"`
+	Token     string   `json:"token"`
+	Stop      int      `json:"stop"`
+	Msg       string   `json:"msg"`
+	Code      string   `json:"code"`
+	Severity  Severity `json:"severity"`
+}
+
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("line %d:%d %q", e.Line, e.Column, e.Msg)
+}
+
+func newSyntaxError(line, column int, code, msg string) SyntaxError {
+	return SyntaxError{
+		line, column, line, column, "", 0, msg, code, SeverityError,
+	}
+}
+
+// tokenEnd returns the line and column right after the text of a token which
+// starts at a line and column.
+func tokenEnd(line, column int, text string) (int, int) {
+	if i := strings.LastIndex(text, "\n"); i >= 0 {
+		return line + strings.Count(text, "\n"), utf8.RuneCountInString(
+			text[i+1:],
+		)
+	}
+	return line, column + utf8.RuneCountInString(text)
 }
 
-func (e wdlSyntaxError) Error() string {
-	return fmt.Sprintf("line %d:%d %q", e.line, e.column, e.msg)
+// offendingToken returns the source text a syntax error is on, and the offset
+// of its last character, which is the offending token of the parser, or the
+// unrecognized text of the lexer.
+func offendingToken(
+	recognizer antlr.Recognizer, offendingSymbol interface{},
+) (string, int) {
+	if t, ok := offendingSymbol.(antlr.Token); ok {
+		if t.GetTokenType() == antlr.TokenEOF {
+			return "", t.GetStop()
+		}
+		return t.GetText(), t.GetStop()
+	}
+	if l, ok := recognizer.(*antlr.BaseLexer); ok {
+		stop := l.GetInputStream().Index()
+		return l.GetInputStream().GetText(l.TokenStartCharIndex, stop), stop
+	}
+	return "", 0
 }
 
-func newWdlSyntaxError(line, column int, msg string) wdlSyntaxError {
-	return wdlSyntaxError{line, column, msg}
+// newReadError returns an error on a document or directory failing to be
+// read, which has no position.
+func newReadError(err error) SyntaxError {
+	return newSyntaxError(0, 0, CodeUnreadable, err.Error())
 }
 
+// errFailFast is panicked by an error listener in fail fast mode to abort
+// parsing at the first syntax error.
+type errFailFast struct{}
+
+// A position is a line and column in a WDL document.
+type position struct{ line, column int }
+
 type wdlErrorListener struct {
 	*antlr.DiagnosticErrorListener
-	syntaxErrors []wdlSyntaxError
+	syntaxErrors []SyntaxError
+	failFast     bool
+	// positions right after non-ASCII letters, where parser errors caused by
+	// the letters breaking identifiers are not reported again
+	afterNonASCII map[position]bool
+	// ambiguity and full context reports of the parser, if collected
+	ambiguities *[]string
+	reporting   bool // whether a report is being made, not a syntax error
+}
+
+func newWdlErrorListener(exactOnly, failFast bool) *wdlErrorListener {
+	return &wdlErrorListener{
+		DiagnosticErrorListener: antlr.NewDiagnosticErrorListener(exactOnly),
+		failFast:                failFast,
+		afterNonASCII:           map[position]bool{},
+	}
+}
+
+// unrecognizedPrefix starts a message of the lexer on text it can't recognize.
+const unrecognizedPrefix = "token recognition error at: '"
+
+// nonASCIILetter returns the non-ASCII letter a token recognition error of
+// the lexer is about, if it is.
+func nonASCIILetter(msg string) (rune, bool) {
+	if !strings.HasPrefix(msg, unrecognizedPrefix) {
+		return 0, false
+	}
+	r, _ := utf8.DecodeRuneInString(strings.TrimPrefix(msg, unrecognizedPrefix))
+	return r, r > unicode.MaxASCII && unicode.IsLetter(r)
+}
+
+// earlierKeyword returns the keyword of a type read before an offending
+// keyword of the type, if there is one, as WDL documents can't have a second
+// workflow or version statement.
+func earlierKeyword(
+	recognizer antlr.Recognizer, offendingSymbol interface{}, tokenType int,
+) (antlr.Token, bool) {
+	t, ok := offendingSymbol.(antlr.Token)
+	if !ok || t.GetTokenType() != tokenType {
+		return nil, false
+	}
+	p, ok := recognizer.(antlr.Parser)
+	if !ok {
+		return nil, false
+	}
+	stream := p.GetTokenStream()
+	for i := 0; i < t.GetTokenIndex(); i++ {
+		if prev := stream.Get(i); prev.GetTokenType() == t.GetTokenType() {
+			return prev, true
+		}
+	}
+	return nil, false
+}
+
+// missingVersion reports whether an offending token is where the version
+// statement is expected, which is the first statement of a document.
+func missingVersion(
+	recognizer antlr.Recognizer, offendingSymbol interface{},
+) bool {
+	t, ok := offendingSymbol.(antlr.Token)
+	if !ok || t.GetTokenType() == parser.WdlV1_1ParserVERSION {
+		return false
+	}
+	p, ok := recognizer.(antlr.Parser)
+	return ok && p.IsExpectedToken(parser.WdlV1_1ParserVERSION)
+}
+
+// isVersion reports whether an offending symbol is a version keyword.
+func isVersion(offendingSymbol interface{}) bool {
+	t, ok := offendingSymbol.(antlr.Token)
+	return ok && t.GetTokenType() == parser.WdlV1_1ParserVERSION
 }
 
-func newWdlErrorListener(exactOnly bool) *wdlErrorListener {
-	return &wdlErrorListener{antlr.NewDiagnosticErrorListener(exactOnly), nil}
+// versionError returns the index of the syntax error on a missing version
+// statement, if it's been collected.
+func (l *wdlErrorListener) versionError() (int, bool) {
+	for i, err := range l.syntaxErrors {
+		if err.Code == CodeVersion {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// keywordAsName returns the text of a keyword if an offending token is the
+// keyword used as a name, which is where an identifier is expected right after
+// a type or a keyword introducing a name, like call or as.
+func keywordAsName(
+	recognizer antlr.Recognizer, offendingSymbol interface{},
+) (string, bool) {
+	t, ok := offendingSymbol.(antlr.Token)
+	if !ok {
+		return "", false
+	}
+	p, ok := recognizer.(antlr.Parser)
+	if !ok || !p.IsExpectedToken(parser.WdlV1_1ParserIdentifier) {
+		return "", false
+	}
+	literals := p.GetLiteralNames()
+	if t.GetTokenType() <= 0 || t.GetTokenType() >= len(literals) ||
+		!keywordLiteral.MatchString(literals[t.GetTokenType()]) {
+		return "", false
+	}
+	stream := p.GetTokenStream()
+	for i := t.GetTokenIndex() - 1; i >= 0; i-- {
+		prev := stream.Get(i)
+		if prev.GetChannel() != antlr.TokenDefaultChannel {
+			continue
+		}
+		switch prev.GetTokenType() {
+		case parser.WdlV1_1ParserSTRING, parser.WdlV1_1ParserFILE,
+			parser.WdlV1_1ParserBOOLEAN, parser.WdlV1_1ParserINT,
+			parser.WdlV1_1ParserFLOAT, parser.WdlV1_1ParserOBJECT,
+			parser.WdlV1_1ParserIdentifier, parser.WdlV1_1ParserRBRACK,
+			parser.WdlV1_1ParserPLUS, parser.WdlV1_1ParserOPTIONAL,
+			parser.WdlV1_1ParserWORKFLOW, parser.WdlV1_1ParserTASK,
+			parser.WdlV1_1ParserSTRUCT, parser.WdlV1_1ParserCALL,
+			parser.WdlV1_1ParserAS, parser.WdlV1_1ParserAFTER:
+			return t.GetText(), true
+		}
+		return "", false
+	}
+	return "", false
+}
+
+// misplacedMeta returns the text of a meta or parameter_meta keyword if an
+// offending token is one out of a workflow or task, where WDL 1.1 has no
+// metadata sections, and reports whether it's in a struct.
+func misplacedMeta(
+	recognizer antlr.Recognizer, offendingSymbol interface{},
+) (kw string, inStruct, ok bool) {
+	t, ok := offendingSymbol.(antlr.Token)
+	if !ok {
+		return "", false, false
+	}
+	switch t.GetTokenType() {
+	case parser.WdlV1_1ParserMETA, parser.WdlV1_1ParserPARAMETERMETA:
+	default:
+		return "", false, false
+	}
+	p, ok := recognizer.(antlr.Parser)
+	if !ok {
+		return "", false, false
+	}
+	var tree antlr.Tree = p.GetParserRuleContext()
+	for ; tree != nil; tree = tree.GetParent() {
+		switch tree.(type) {
+		case *parser.WorkflowContext, *parser.TaskContext:
+			return "", false, false
+		case *parser.Wdl_structContext:
+			return t.GetText(), true, true
+		}
+	}
+	return t.GetText(), false, true
+}
+
+// keywordLiteral matches a literal name of a keyword token, like 'scatter'.
+var keywordLiteral = regexp.MustCompile(`^'[A-Za-z_]+'$`)
+
+// dropAfter drops syntax errors collected after a position, which are
+// reported by the lexer reading ahead before the parser reports an error at
+// the position, and make no sense once it's found.
+func (l *wdlErrorListener) dropAfter(line, column int) {
+	kept := l.syntaxErrors[:0]
+	for _, err := range l.syntaxErrors {
+		if err.Line < line || err.Line == line && err.Column < column {
+			kept = append(kept, err)
+		}
+	}
+	l.syntaxErrors = kept
 }
 
 func (l *wdlErrorListener) SyntaxError(
@@ -37,7 +292,147 @@ func (l *wdlErrorListener) SyntaxError(
 	msg string,
 	e antlr.RecognitionException,
 ) {
-	l.syntaxErrors = append(
-		l.syntaxErrors, newWdlSyntaxError(line, column, msg),
-	)
+	if l.reporting {
+		*l.ambiguities = append(*l.ambiguities, msg)
+		return
+	}
+	code := CodeSyntax
+	text, stop := offendingToken(recognizer, offendingSymbol)
+	if t, ok := offendingSymbol.(antlr.Token); ok &&
+		t.GetTokenType() == parser.WdlV1_1ParserAS {
+		// as is only valid in imports and calls, so it's likely meant as a
+		// cast, which WDL 1.1 doesn't have.
+		code = CodeCast
+		msg = `"as" can't cast a value since WDL 1.1 has no cast; ` +
+			`a value is coerced to the type it's declared as`
+	} else if kw, inStruct, ok := misplacedMeta(
+		recognizer, offendingSymbol,
+	); ok {
+		code = CodeMisplacedMeta
+		msg = fmt.Sprintf(
+			"%q section is dropped since WDL 1.1 only has metadata "+
+				"in workflows and tasks", kw,
+		)
+		if inStruct {
+			msg = fmt.Sprintf(
+				"%q section is dropped since WDL 1.1 has no struct "+
+					"metadata, which is new in WDL development", kw,
+			)
+		}
+		// Metadata keywords switch the lexer into another mode, which
+		// garbles the rest of the line.
+		l.dropAfter(line, column)
+	} else if kw, ok := keywordAsName(recognizer, offendingSymbol); ok {
+		code = CodeReservedKeyword
+		msg = fmt.Sprintf(
+			"%q is a reserved keyword and can't be used as a name", kw,
+		)
+		// command and metadata keywords switch the lexer into another
+		// mode, which garbles the rest of the line.
+		l.dropAfter(line, column)
+	} else if first, ok := earlierKeyword(
+		recognizer, offendingSymbol, parser.WdlV1_1ParserWORKFLOW,
+	); ok {
+		code = CodeDuplicateWorkflow
+		msg = fmt.Sprintf(
+			"a document can only have one workflow, which is at line %d:%d",
+			first.GetLine(), first.GetColumn(),
+		)
+	} else if missingVersion(recognizer, offendingSymbol) {
+		code = CodeVersion
+		msg = `a document must start with a version statement, ` +
+			`like "version 1.1"`
+	} else if first, ok := earlierKeyword(
+		recognizer, offendingSymbol, parser.WdlV1_1ParserVERSION,
+	); ok {
+		code = CodeVersion
+		msg = fmt.Sprintf(
+			"a document can only have one version statement, "+
+				"which is at line %d:%d",
+			first.GetLine(), first.GetColumn(),
+		)
+	} else if i, ok := l.versionError(); ok && isVersion(offendingSymbol) {
+		// The version statement is found after what it's missing before,
+		// which is reported again on the version statement itself.
+		missing := l.syntaxErrors[i]
+		l.syntaxErrors = append(l.syntaxErrors[:i], l.syntaxErrors[i+1:]...)
+		code = CodeVersion
+		msg = fmt.Sprintf(
+			"version statement must be the first statement, "+
+				"but %q at line %d:%d comes before it",
+			missing.Token, missing.Line, missing.Column,
+		)
+	} else if r, ok := nonASCIILetter(msg); ok {
+		// WDL identifiers are ASCII only while the lexer reports a
+		// non-ASCII letter merely as an unrecognized token.
+		code = CodeNonASCII
+		msg = fmt.Sprintf(
+			"non-ASCII letter %q is not allowed in identifiers", r,
+		)
+		l.afterNonASCII[position{line, column + 1}] = true
+	} else if t, ok := offendingSymbol.(antlr.Token); ok &&
+		l.afterNonASCII[position{t.GetLine(), t.GetColumn()}] {
+		return
+	}
+	err := newSyntaxError(line, column, code, msg)
+	err.EndLine, err.EndColumn = tokenEnd(line, column, text)
+	err.Token, err.Stop = text, stop
+	l.syntaxErrors = append(l.syntaxErrors, err)
+	if l.failFast {
+		panic(errFailFast{})
+	}
+}
+
+// report runs a report of the embedded DiagnosticErrorListener, which notifies
+// listeners of it like a syntax error, so that it's collected as an ambiguity
+// instead. It's ignored unless ambiguities are collected.
+func (l *wdlErrorListener) report(r func()) {
+	if l.ambiguities == nil {
+		return
+	}
+	l.reporting = true
+	defer func() { l.reporting = false }()
+	r()
+}
+
+func (l *wdlErrorListener) ReportAmbiguity(
+	recognizer antlr.Parser,
+	dfa *antlr.DFA,
+	startIndex, stopIndex int,
+	exact bool,
+	ambigAlts *antlr.BitSet,
+	configs antlr.ATNConfigSet,
+) {
+	l.report(func() {
+		l.DiagnosticErrorListener.ReportAmbiguity(
+			recognizer, dfa, startIndex, stopIndex, exact, ambigAlts, configs,
+		)
+	})
+}
+
+func (l *wdlErrorListener) ReportAttemptingFullContext(
+	recognizer antlr.Parser,
+	dfa *antlr.DFA,
+	startIndex, stopIndex int,
+	conflictingAlts *antlr.BitSet,
+	configs antlr.ATNConfigSet,
+) {
+	l.report(func() {
+		l.DiagnosticErrorListener.ReportAttemptingFullContext(
+			recognizer, dfa, startIndex, stopIndex, conflictingAlts, configs,
+		)
+	})
+}
+
+func (l *wdlErrorListener) ReportContextSensitivity(
+	recognizer antlr.Parser,
+	dfa *antlr.DFA,
+	startIndex, stopIndex, prediction int,
+	configs antlr.ATNConfigSet,
+) {
+	l.report(func() {
+		l.DiagnosticErrorListener.ReportContextSensitivity(
+			recognizer, dfa, startIndex, stopIndex, prediction, configs,
+		)
+	})
 }
@@ -0,0 +1,379 @@
+package wdlparser
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// evaluate computes the value of an RPN where identifiers take their values
+// from env. All operands are evaluated, including both branches of a ternary.
+func (e exprRPN) evaluate(env map[string]value) (value, error) {
+	var stack []value
+	pop := func(n int) ([]value, error) {
+		if len(stack) < n {
+			return nil, fmt.Errorf("missing operands in %v", e)
+		}
+		operands := stack[len(stack)-n:]
+		stack = stack[:len(stack)-n]
+		return operands, nil
+	}
+	for _, elem := range e {
+		var v value
+		var err error
+		switch elem := elem.(type) {
+		case value:
+			v = elem
+		case *identifier:
+			var ok bool
+			if v, ok = env[elem.initialName]; !ok {
+				err = fmt.Errorf("%q is not defined", elem.initialName)
+			}
+		case *expression:
+			v, err = elem.evaluate(env)
+		case WDLOpSym:
+			var operands []value
+			switch elem {
+			case WDLNeg, WDLNot, WDLStr:
+				operands, err = pop(1)
+			case WDLTernary:
+				operands, err = pop(3)
+			default:
+				operands, err = pop(2)
+			}
+			if err == nil {
+				v, err = operate(elem, operands)
+			}
+		case nAryOp:
+			var operands []value
+			if operands, err = pop(elem.n); err == nil {
+				v, err = construct(elem.op, operands)
+			}
+		default:
+			err = fmt.Errorf("cannot evaluate %v", elem)
+		}
+		if err != nil {
+			return value{}, err
+		}
+		stack = append(stack, v)
+	}
+	if len(stack) != 1 {
+		return value{}, fmt.Errorf("cannot evaluate %v", e)
+	}
+	return stack[0], nil
+}
+
+// evaluate computes the value of an expression, applying placeholder options
+// if any.
+func (e *expression) evaluate(env map[string]value) (value, error) {
+	v, err := e.rpn.evaluate(env)
+	if len(e.options) == 0 {
+		return v, err
+	}
+	if d, ok := e.options["default"]; ok && (err != nil || v.govalue == nil) {
+		return d, nil
+	}
+	if err != nil {
+		return v, err
+	}
+	if b, ok := v.govalue.(bool); ok {
+		if s, ok := e.options[strconv.FormatBool(b)]; ok {
+			return s, nil
+		}
+	}
+	if sep, ok := e.options["sep"]; ok {
+		if elems, ok := v.govalue.([]value); ok {
+			strs := make([]string, 0, len(elems))
+			for _, elem := range elems {
+				strs = append(strs, stringify(elem))
+			}
+			return value{String, strings.Join(strs, stringify(sep))}, nil
+		}
+	}
+	return v, nil
+}
+
+// stringify converts a value to string as it's interpolated into a string.
+func stringify(v value) string {
+	switch g := v.govalue.(type) {
+	case nil:
+		return ""
+	case string:
+		return g
+	case int64:
+		return strconv.FormatInt(g, 10)
+	case float64:
+		return strconv.FormatFloat(g, 'f', 6, 64)
+	case bool:
+		return strconv.FormatBool(g)
+	}
+	return fmt.Sprint(v.govalue)
+}
+
+// CoerceTo converts a value to a type as WDL does when the value is bound to
+// a declaration of the type. Besides what coercible allows, like Int to Float
+// and String to File or Directory, an Int, Float or Boolean is coerced to
+// String as it's interpolated into a string. Arrays are coerced element by
+// element, maps key by key and value by value, and None only to optional
+// types. A value of an optional type is coerced as a value of its base type.
+// A File is never coerced to a Directory nor the other way around. An error
+// is returned for any other coercion.
+func (v value) CoerceTo(t Type) (value, error) {
+	fail := func() (value, error) {
+		return value{}, fmt.Errorf("cannot coerce %s to %s", v, t.typeString())
+	}
+	if t == v.typ {
+		return v, nil
+	}
+	if o, ok := t.(optional); ok {
+		if v.govalue == nil {
+			return value{t, nil}, nil
+		}
+		c, err := v.CoerceTo(o.base)
+		if err != nil {
+			return fail()
+		}
+		return value{t, c.govalue}, nil
+	}
+	if v.govalue == nil {
+		return fail()
+	}
+	if o, ok := v.typ.(optional); ok {
+		c, err := value{o.base, v.govalue}.CoerceTo(t)
+		if err != nil {
+			return fail()
+		}
+		return c, nil
+	}
+	if t == Any {
+		return v, nil
+	}
+	switch t := t.(type) {
+	case primitive:
+		switch g := v.govalue.(type) {
+		case int64:
+			if t == Float {
+				return value{Float, float64(g)}, nil
+			}
+			if t == String {
+				return value{String, stringify(v)}, nil
+			}
+		case float64, bool:
+			if t == String {
+				return value{String, stringify(v)}, nil
+			}
+		case string:
+			switch {
+			case t == String, v.typ == String && t == File,
+				v.typ == String && t == Directory:
+				return value{t, g}, nil
+			}
+		}
+	case array:
+		elems, ok := v.govalue.([]value)
+		if !ok {
+			return fail()
+		}
+		coerced := make([]value, 0, len(elems))
+		for _, elem := range elems {
+			c, err := elem.CoerceTo(t.elem)
+			if err != nil {
+				return fail()
+			}
+			coerced = append(coerced, c)
+		}
+		return value{t, coerced}, nil
+	case mapping:
+		entries, ok := v.govalue.([]mapEntry)
+		if !ok {
+			return fail()
+		}
+		coerced := make([]mapEntry, 0, len(entries))
+		for _, e := range entries {
+			key, err := e.key.CoerceTo(t.key)
+			if err != nil {
+				return fail()
+			}
+			val, err := e.value.CoerceTo(t.value)
+			if err != nil {
+				return fail()
+			}
+			coerced = append(coerced, mapEntry{key, val})
+		}
+		return value{t, coerced}, nil
+	}
+	return fail()
+}
+
+// numbers converts numeric operands to float64 and reports whether both are
+// Int.
+func numbers(a, b value) (x, y float64, ints bool, err error) {
+	toFloat := func(v value) (float64, error) {
+		switch g := v.govalue.(type) {
+		case int64:
+			return float64(g), nil
+		case float64:
+			return g, nil
+		}
+		return 0, fmt.Errorf("%v is not a number", v.govalue)
+	}
+	if x, err = toFloat(a); err != nil {
+		return
+	}
+	if y, err = toFloat(b); err != nil {
+		return
+	}
+	return x, y, a.typ == Int && b.typ == Int, nil
+}
+
+// operate applies an operator to its operands.
+func operate(op WDLOpSym, operands []value) (value, error) {
+	switch op {
+	case WDLStr:
+		return value{String, stringify(operands[0])}, nil
+	case WDLNot:
+		b, ok := operands[0].govalue.(bool)
+		if !ok {
+			return value{}, fmt.Errorf("cannot negate %v", operands[0].govalue)
+		}
+		return value{Boolean, !b}, nil
+	case WDLNeg:
+		switch g := operands[0].govalue.(type) {
+		case int64:
+			return value{Int, -g}, nil
+		case float64:
+			return value{Float, -g}, nil
+		}
+		return value{}, fmt.Errorf("cannot negate %v", operands[0].govalue)
+	case WDLTernary:
+		b, ok := operands[0].govalue.(bool)
+		if !ok {
+			return value{}, fmt.Errorf(
+				"condition %v is not a Boolean", operands[0].govalue,
+			)
+		}
+		if b {
+			return operands[1], nil
+		}
+		return operands[2], nil
+	case WDLAnd, WDLOr:
+		a, okA := operands[0].govalue.(bool)
+		b, okB := operands[1].govalue.(bool)
+		if !okA || !okB {
+			return value{}, fmt.Errorf("%s needs Boolean operands", op)
+		}
+		if op == WDLAnd {
+			return value{Boolean, a && b}, nil
+		}
+		return value{Boolean, a || b}, nil
+	}
+
+	a, b := operands[0], operands[1]
+	_, aIsString := a.govalue.(string)
+	_, bIsString := b.govalue.(string)
+	if op == WDLAdd && (aIsString || bIsString) {
+		return value{String, stringify(a) + stringify(b)}, nil
+	}
+	if aIsString && bIsString {
+		x, y := a.govalue.(string), b.govalue.(string)
+		switch op {
+		case WDLEq:
+			return value{Boolean, x == y}, nil
+		case WDLNeq:
+			return value{Boolean, x != y}, nil
+		case WDLLt:
+			return value{Boolean, x < y}, nil
+		case WDLLte:
+			return value{Boolean, x <= y}, nil
+		case WDLGt:
+			return value{Boolean, x > y}, nil
+		case WDLGte:
+			return value{Boolean, x >= y}, nil
+		}
+	}
+	x, y, ints, err := numbers(a, b)
+	switch {
+	case op == WDLEq && err != nil:
+		return value{Boolean, reflect.DeepEqual(a.govalue, b.govalue)}, nil
+	case op == WDLNeq && err != nil:
+		return value{Boolean, !reflect.DeepEqual(a.govalue, b.govalue)}, nil
+	case err != nil:
+		return value{}, err
+	}
+	switch op {
+	case WDLEq:
+		return value{Boolean, x == y}, nil
+	case WDLNeq:
+		return value{Boolean, x != y}, nil
+	case WDLLt:
+		return value{Boolean, x < y}, nil
+	case WDLLte:
+		return value{Boolean, x <= y}, nil
+	case WDLGt:
+		return value{Boolean, x > y}, nil
+	case WDLGte:
+		return value{Boolean, x >= y}, nil
+	}
+	if ints {
+		i, j := a.govalue.(int64), b.govalue.(int64)
+		switch op {
+		case WDLAdd:
+			return value{Int, i + j}, nil
+		case WDLSub:
+			return value{Int, i - j}, nil
+		case WDLMul:
+			return value{Int, i * j}, nil
+		case WDLDiv, WDLMod:
+			if j == 0 {
+				return value{}, fmt.Errorf("division by zero")
+			}
+			if op == WDLDiv {
+				return value{Int, i / j}, nil
+			}
+			return value{Int, i % j}, nil
+		}
+	}
+	switch op {
+	case WDLAdd:
+		return value{Float, x + y}, nil
+	case WDLSub:
+		return value{Float, x - y}, nil
+	case WDLMul:
+		return value{Float, x * y}, nil
+	case WDLDiv:
+		return value{Float, x / y}, nil
+	}
+	return value{}, fmt.Errorf("cannot apply %s to %v and %v", op, x, y)
+}
+
+// construct builds an array out of its elements, or a map out of its keys
+// and values, which alternate in elems.
+func construct(op WDLOpSym, elems []value) (value, error) {
+	unified := func(vs []value) Type {
+		types := make([]Type, 0, len(vs))
+		for _, v := range vs {
+			types = append(types, v.typ)
+		}
+		typ, ok := unify(types)
+		if !ok || typ == nil {
+			return Any
+		}
+		return typ
+	}
+	switch op {
+	case WDLArray:
+		typ := ArrayOf(unified(elems))
+		return value{typ, append([]value(nil), elems...)}, nil
+	case WDLMap:
+		var keys, values []value
+		entries := make([]mapEntry, 0, len(elems)/2)
+		for i := 0; i+1 < len(elems); i += 2 {
+			keys = append(keys, elems[i])
+			values = append(values, elems[i+1])
+			entries = append(entries, mapEntry{elems[i], elems[i+1]})
+		}
+		return value{MapOf(unified(keys), unified(values)), entries}, nil
+	}
+	return value{}, fmt.Errorf("cannot evaluate %s literal", op)
+}
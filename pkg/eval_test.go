@@ -0,0 +1,161 @@
+package wdlparser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEvaluate(t *testing.T) {
+	env := map[string]value{
+		"x":     {Int, int64(1)},
+		"y":     {Int, int64(2)},
+		"z":     {Int, int64(3)},
+		"flag":  {Boolean, true},
+		"files": {ArrayOf(String), []value{{String, "a"}, {String, "b"}}},
+	}
+	testCases := []struct {
+		expr string
+		want value
+	}{
+		{`"a~{x}b~{y}c~{z}d"`, value{String, "a1b2c3d"}},
+		{`"~{x}~{y}~{z}"`, value{String, "123"}},
+		{`"~{x} + ~{y} = ~{x + y}"`, value{String, "1 + 2 = 3"}},
+		{`"-f ~{sep=' -f ' files}"`, value{String, "-f a -f b"}},
+		{`"~{true='--flag' false='' flag}"`, value{String, "--flag"}},
+		{`"~{default='none' missing}"`, value{String, "none"}},
+		{"3+4*2/(1-5*2)+3", value{Int, int64(6)}},
+		{"1 + 2.5", value{Float, 3.5}},
+		{"if x > 0 then y else z", value{Int, int64(2)}},
+		{"x == 1.0 && !(y < x)", value{Boolean, true}},
+		{
+			`{"a": x, "b": y}`,
+			value{MapOf(String, Int), []mapEntry{
+				{value{String, "a"}, value{Int, int64(1)}},
+				{value{String, "b"}, value{Int, int64(2)}},
+			}},
+		},
+	}
+	for _, tc := range testCases {
+		wdl := "version 1.1 workflow Test {input{String t = " + tc.expr + "}}"
+		result, err := Antlr4Parse(wdl)
+		if err != nil {
+			t.Errorf("Found %d errors in %q, expect no errors", len(err), wdl)
+		}
+		v, e := result.Workflow.Inputs[0].value.evaluate(env)
+		if e != nil {
+			t.Errorf("failed to evaluate %s: %v", tc.expr, e)
+		}
+		if diff := cmp.Diff(
+			tc.want, v, cmp.AllowUnexported(value{}, mapping{}, mapEntry{}),
+		); diff != "" {
+			t.Errorf("unexpected value of %s:\n%s", tc.expr, diff)
+		}
+	}
+}
+
+func TestCoerceTo(t *testing.T) {
+	testCases := []struct {
+		v    value
+		typ  Type
+		want value
+		err  bool
+	}{
+		{value{Int, int64(3)}, Float, value{Float, 3.0}, false},
+		{value{Int, int64(3)}, String, value{String, "3"}, false},
+		{value{Float, 2.5}, String, value{String, "2.500000"}, false},
+		{value{Boolean, true}, String, value{String, "true"}, false},
+		{value{String, "a.txt"}, File, value{File, "a.txt"}, false},
+		{value{File, "a.txt"}, String, value{String, "a.txt"}, false},
+		{value{String, "data/"}, Directory, value{Directory, "data/"}, false},
+		{value{Directory, "data/"}, String, value{String, "data/"}, false},
+		{value{File, "a.txt"}, Directory, value{}, true},
+		{value{Directory, "data/"}, File, value{}, true},
+		{value{Int, int64(3)}, Int, value{Int, int64(3)}, false},
+		{value{Int, int64(3)}, Any, value{Int, int64(3)}, false},
+		{
+			value{Int, int64(3)},
+			OptionalOf(Float),
+			value{OptionalOf(Float), 3.0},
+			false,
+		},
+		{value{None, nil}, OptionalOf(Int), value{OptionalOf(Int), nil}, false},
+		{
+			value{OptionalOf(Int), int64(3)},
+			OptionalOf(Int),
+			value{OptionalOf(Int), int64(3)},
+			false,
+		},
+		{value{OptionalOf(Int), int64(3)}, Int, value{Int, int64(3)}, false},
+		{
+			value{OptionalOf(Float), 3.0},
+			OptionalOf(Float),
+			value{OptionalOf(Float), 3.0},
+			false,
+		},
+		{value{OptionalOf(Int), nil}, Int, value{}, true},
+		{
+			value{
+				MapOf(String, Int),
+				[]mapEntry{{value{String, "a"}, value{Int, int64(1)}}},
+			},
+			MapOf(String, Float),
+			value{
+				MapOf(String, Float),
+				[]mapEntry{{value{String, "a"}, value{Float, 1.0}}},
+			},
+			false,
+		},
+		{
+			value{
+				MapOf(String, String),
+				[]mapEntry{{value{String, "a"}, value{String, "b"}}},
+			},
+			MapOf(String, Int),
+			value{},
+			true,
+		},
+		{
+			value{ArrayOf(Int), []value{{Int, int64(1)}, {Int, int64(2)}}},
+			ArrayOf(Float),
+			value{ArrayOf(Float), []value{{Float, 1.0}, {Float, 2.0}}},
+			false,
+		},
+		{
+			value{ArrayOf(Any), []value{}},
+			ArrayOf(File),
+			value{ArrayOf(File), []value{}},
+			false,
+		},
+		{value{Float, 2.5}, Int, value{}, true},
+		{value{String, "3"}, Int, value{}, true},
+		{value{Boolean, true}, Int, value{}, true},
+		{value{None, nil}, Int, value{}, true},
+		{value{Int, int64(1)}, ArrayOf(Int), value{}, true},
+		{
+			value{ArrayOf(String), []value{{String, "a"}}},
+			ArrayOf(Int),
+			value{},
+			true,
+		},
+	}
+	for _, tc := range testCases {
+		got, err := tc.v.CoerceTo(tc.typ)
+		if (err != nil) != tc.err {
+			t.Errorf(
+				"unexpected error coercing %s to %s: %v",
+				tc.v, tc.typ.typeString(), err,
+			)
+		}
+		if diff := cmp.Diff(
+			tc.want, got, cmp.AllowUnexported(
+				value{}, array{}, mapping{}, optional{}, mapEntry{},
+			),
+		); diff != "" {
+			t.Errorf(
+				"unexpected coercion of %s to %s:\n%s",
+				tc.v, tc.typ.typeString(), diff,
+			)
+		}
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 
+	"github.com/antlr/antlr4/runtime/Go/antlr"
 	parser "github.com/yunhailuo/wdlparser/pkg/antlr4_grammar/1_1"
 )
 
@@ -15,10 +17,101 @@ func (e *exprRPN) append(elem interface{}) {
 	*e = append(*e, elem)
 }
 
+// StringValue returns the string a constant string expression, which refers to
+// no declaration, evaluates to. Parts of a string split by placeholders are
+// concatenated, like "4 GB" for "~{4} GB". It reports false for any other
+// expression, including a placeholder falling back to its default, like
+// "~{default='a' x}".
+func (e *exprRPN) StringValue() (string, bool) {
+	v, ok := e.constant()
+	if !ok {
+		return "", false
+	}
+	s, ok := v.govalue.(string)
+	return s, ok
+}
+
+// constant returns the value of an RPN which refers to no declaration. It
+// reports false if the RPN refers to any, even if it evaluates without them
+// through a default placeholder option, or can't be evaluated.
+func (e *exprRPN) constant() (value, bool) {
+	if e == nil || len(*e) == 0 || e.hasReference() {
+		return value{}, false
+	}
+	v, err := e.evaluate(nil)
+	return v, err == nil
+}
+
+// adopt makes a node holding an RPN the parent of the expressions within it.
+func (e *exprRPN) adopt(parent node) {
+	for _, elem := range *e {
+		if sub, ok := elem.(*expression); ok {
+			sub.setParent(parent)
+		}
+	}
+}
+
+// References returns identifiers an RPN refers to, including those in its
+// sub-expressions, in source order. For a member access like call.output, it's
+// the identifier of the accessed operand, like call.
+func (e *exprRPN) References() []*identifier {
+	var refs []*identifier
+	for _, elem := range *e {
+		switch elem := elem.(type) {
+		case *identifier:
+			refs = append(refs, elem)
+		case *expression:
+			refs = append(refs, elem.rpn.References()...)
+		}
+	}
+	return refs
+}
+
+// hasReference reports whether an RPN refers to any declaration.
+func (e *exprRPN) hasReference() bool {
+	return len(e.References()) > 0
+}
+
+// callsFunction reports whether an RPN calls any of the named functions.
+func (e *exprRPN) callsFunction(names ...string) bool {
+	for _, elem := range *e {
+		switch elem := elem.(type) {
+		case function:
+			for _, name := range names {
+				if elem.name == name {
+					return true
+				}
+			}
+		case *expression:
+			if elem.rpn.callsFunction(names...) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// literal returns the string an RPN holds if it's a constant string.
+func (e *exprRPN) literal() string {
+	s, _ := e.StringValue()
+	return s
+}
+
 type expression struct {
 	genNode
 	rpn      exprRPN
 	subExprs exprStack
+	options  map[string]value // placeholder options: sep, default, true, false
+}
+
+// Option returns the value of a placeholder option, like sep, default, true
+// or false, as written in the placeholder.
+func (e *expression) Option(name string) (string, bool) {
+	v, ok := e.options[name]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprint(v.govalue), true
 }
 
 func newExpression(start, end int) *expression {
@@ -41,7 +134,7 @@ func (s *exprStack) pop() *expression {
 		*s = (*s)[:stackDepth-1]
 		return e
 	}
-	log.Fatalf("pop error: expression stack %v is empty", *s)
+	log.Panicf("pop error: expression stack %v is empty", *s)
 	return nil
 }
 
@@ -62,14 +155,98 @@ const (
 	String  = primitive("String")
 	File    = primitive("File")
 	Any     = primitive("Any")
+
+	// Directory is a type of WDL development, which the WDL 1.1 grammar
+	// reads as a struct name, like Directory d = "data/".
+	Directory = primitive("Directory")
 )
 
+// None is the type of the None literal, an optional value of any type. It's
+// coercible to any optional type but to no other type.
+var None Type = OptionalOf(Any)
+
+// An array is the type of WDL arrays.
+type array struct {
+	elem Type
+}
+
+func (a array) typeString() string {
+	return "Array[" + a.elem.typeString() + "]"
+}
+
+// ArrayOf returns the array type with elements of a given type.
+func ArrayOf(elem Type) Type { return array{elem} }
+
+// A mapping is the type of WDL maps.
+type mapping struct {
+	key, value Type
+}
+
+func (m mapping) typeString() string {
+	return "Map[" + m.key.typeString() + "," + m.value.typeString() + "]"
+}
+
+// MapOf returns the map type with keys and values of given types.
+func MapOf(key, value Type) Type { return mapping{key, value} }
+
+// An optional is the type of WDL optional values, like Int?.
+type optional struct {
+	base Type
+}
+
+func (o optional) typeString() string { return o.base.typeString() + "?" }
+
+// OptionalOf returns the optional type of a given type.
+func OptionalOf(base Type) Type {
+	if o, ok := base.(optional); ok {
+		return o
+	}
+	return optional{base}
+}
+
 // A value represents a value in WDL.
 type value struct {
 	typ     Type
 	govalue interface{} // actual underlying go value
 }
 
+// A mapEntry is a key-value pair of a map value, whose go value is a slice of
+// entries in the order they are written.
+type mapEntry struct {
+	key, value value
+}
+
+// String renders a value with its type, like Int(3), String("abc") or
+// Array[Int]([Int(1), Int(2)]), and None for None, so that values read well
+// in dumps of RPNs and test failures.
+func (v value) String() string {
+	if v.govalue == nil {
+		return "None"
+	}
+	typ := "?"
+	if v.typ != nil {
+		typ = v.typ.typeString()
+	}
+	if elems, ok := v.govalue.([]value); ok {
+		texts := make([]string, 0, len(elems))
+		for _, elem := range elems {
+			texts = append(texts, elem.String())
+		}
+		return typ + "([" + strings.Join(texts, ", ") + "])"
+	}
+	if entries, ok := v.govalue.([]mapEntry); ok {
+		texts := make([]string, 0, len(entries))
+		for _, e := range entries {
+			texts = append(texts, e.key.String()+": "+e.value.String())
+		}
+		return typ + "({" + strings.Join(texts, ", ") + "})"
+	}
+	return typ + "(" + literalInfix(v).text + ")"
+}
+
+// GoString renders a value like String, which %#v uses too.
+func (v value) GoString() string { return v.String() }
+
 func newValue(typ Type, raw string) (value, error) {
 	v := new(value)
 	v.typ = typ
@@ -81,9 +258,9 @@ func newValue(typ Type, raw string) (value, error) {
 		v.govalue, e = strconv.ParseInt(raw, 10, 64)
 	case Float:
 		v.govalue, e = strconv.ParseFloat(raw, 64)
-	case String, File:
+	case String, File, Directory:
 		v.govalue = raw
-	case Any:
+	case Any, None:
 		v.govalue = nil
 	default:
 		v.govalue = nil
@@ -114,8 +291,86 @@ const (
 	WDLAnd     WDLOpSym = "&&"
 	WDLOr      WDLOpSym = "||"
 	WDLTernary WDLOpSym = "?:"
+	WDLArray   WDLOpSym = "[]"
+	WDLMap     WDLOpSym = "{}"
+)
+
+// An Associativity tells how operators of the same precedence group.
+type Associativity int
+
+const (
+	LeftAssociative  Associativity = iota // a - b - c is (a - b) - c
+	RightAssociative                      // - - a is -(-a)
 )
 
+// An opInfo is the precedence and associativity of an operator.
+type opInfo struct {
+	precedence    int
+	associativity Associativity
+}
+
+// primaryPrecedence is the precedence of operands which are not operators,
+// like literals, identifiers, member accesses and function calls, and of
+// operators building such operands, like array literals and interpolation.
+const primaryPrecedence = 8
+
+var operators = map[WDLOpSym]opInfo{
+	WDLTernary: {1, RightAssociative},
+	WDLOr:      {2, LeftAssociative},
+	WDLAnd:     {3, LeftAssociative},
+	WDLEq:      {4, LeftAssociative},
+	WDLNeq:     {4, LeftAssociative},
+	WDLLt:      {4, LeftAssociative},
+	WDLLte:     {4, LeftAssociative},
+	WDLGt:      {4, LeftAssociative},
+	WDLGte:     {4, LeftAssociative},
+	WDLAdd:     {5, LeftAssociative},
+	WDLSub:     {5, LeftAssociative},
+	WDLMul:     {6, LeftAssociative},
+	WDLDiv:     {6, LeftAssociative},
+	WDLMod:     {6, LeftAssociative},
+	WDLNeg:     {7, RightAssociative},
+	WDLNot:     {7, RightAssociative},
+}
+
+// Precedence returns how tightly an operator binds its operands, where a
+// higher precedence binds tighter, as in the WDL grammar. For example, WDLMul
+// binds tighter than WDLAdd.
+func (op WDLOpSym) Precedence() int {
+	if info, ok := operators[op]; ok {
+		return info.precedence
+	}
+	return primaryPrecedence
+}
+
+// Associativity returns how operators of the same precedence as an operator
+// group. All binary operators are left associative.
+func (op WDLOpSym) Associativity() Associativity {
+	if info, ok := operators[op]; ok {
+		return info.associativity
+	}
+	return LeftAssociative
+}
+
+// An nAryOp is an operator taking a variable number of operands, like an array
+// literal. It takes the n elements preceding it in an RPN, where each entry of
+// a map literal counts as a key and a value.
+type nAryOp struct {
+	op WDLOpSym
+	n  int
+}
+
+// A function is a call to a function of the standard library in an RPN,
+// taking the n operands preceding it as arguments.
+type function struct {
+	name string
+	n    int
+}
+
+// A member is a postfix operator in an RPN accessing a member, like a call
+// output or a struct member, of the operand preceding it.
+type member string
+
 // Antlr4 listeners
 
 func (l *wdlv1_1Listener) EnterExpr(ctx *parser.ExprContext) {
@@ -138,24 +393,14 @@ func (l *wdlv1_1Listener) ExitPrimitive_literal(
 	// BoolLiteral of primitive_literal
 	boolToken := ctx.BoolLiteral()
 	if boolToken != nil {
-		v, e := newValue(Boolean, boolToken.GetText())
-		if e == nil {
-			l.astContext.exprNode.rpn.append(v)
-		} else {
-			log.Fatal(e)
-		}
+		l.appendLiteral(Boolean, boolToken.GetSymbol(), boolToken.GetText())
 		return
 	}
 
 	// NONELITERAL of primitive_literal
 	noneToken := ctx.NONELITERAL()
 	if noneToken != nil {
-		v, e := newValue(Any, noneToken.GetText())
-		if e == nil {
-			l.astContext.exprNode.rpn.append(v)
-		} else {
-			log.Fatal(e)
-		}
+		l.appendLiteral(None, noneToken.GetSymbol(), noneToken.GetText())
 		return
 	}
 
@@ -174,42 +419,191 @@ func (l *wdlv1_1Listener) ExitNumber(ctx *parser.NumberContext) {
 	// IntLiteral
 	intToken := ctx.IntLiteral()
 	if intToken != nil {
-		v, e := newValue(Int, intToken.GetText())
-		if e == nil {
-			l.astContext.exprNode.rpn.append(v)
-		} else {
-			log.Fatal(e)
-		}
+		l.appendLiteral(Int, intToken.GetSymbol(), intToken.GetText())
 		return
 	}
 
 	// FloatLiteral
 	floatToken := ctx.FloatLiteral()
 	if floatToken != nil {
-		v, e := newValue(Float, floatToken.GetText())
-		if e == nil {
-			l.astContext.exprNode.rpn.append(v)
-		} else {
-			log.Fatal(e)
-		}
+		l.appendLiteral(Float, floatToken.GetSymbol(), floatToken.GetText())
 		return
 	}
+}
 
-	log.Fatalf("Failed to parse %v: %v", "Number", ctx.GetText())
+// appendLiteral appends the value of a literal on a token to the expression
+// being built. A literal its type can't hold, like an Int beyond 64 bits, is
+// reported as an error on the token and appended as a value without go value,
+// so that the rest of the document is still built.
+func (l *wdlv1_1Listener) appendLiteral(
+	typ Type, token antlr.Token, raw string,
+) {
+	v, err := newValue(typ, raw)
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); ok {
+			err = numErr.Err
+		}
+		text := token.GetText()
+		literalErr := newSyntaxError(
+			token.GetLine(), token.GetColumn(), CodeLiteral, fmt.Sprintf(
+				"%s literal %s is invalid: %v", typ.typeString(), text, err,
+			),
+		)
+		literalErr.Token, literalErr.Stop = text, token.GetStop()
+		literalErr.EndLine, literalErr.EndColumn = tokenEnd(
+			token.GetLine(), token.GetColumn(), text,
+		)
+		l.literalErrors = append(l.literalErrors, literalErr)
+		v = value{typ, nil}
+	}
+	l.astContext.exprNode.rpn.append(v)
 }
 
+// unescape replaces escape sequences in a WDL string literal with the
+// characters they stand for. Unknown escape sequences are kept as written.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case '\\', '"', '\'', '~', '$', '{', '}':
+			b.WriteByte(c)
+		case 'x', 'u', 'U', '0', '1', '2', '3', '4', '5', '6', '7':
+			// \xHH, \uHHHH, \UHHHHHHHH or octal \OOO
+			digits, width, from := "0123456789abcdefABCDEF", 2, i+1
+			switch c {
+			case 'u':
+				width = 4
+			case 'U':
+				width = 8
+			case 'x':
+			default:
+				digits, width, from = "01234567", 3, i
+			}
+			to := from
+			for to < len(s) && to-from < width &&
+				strings.IndexByte(digits, s[to]) >= 0 {
+				to++
+			}
+			base := 16
+			if len(digits) == 8 {
+				base = 8
+			}
+			r, err := strconv.ParseUint(s[from:to], base, 32)
+			if err != nil {
+				b.WriteByte('\\')
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteRune(rune(r))
+			i = to - 1
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// String parts are unescaped while the source text of the whole string is kept
+// as the raw value of a declaration or key/value.
 func (l *wdlv1_1Listener) ExitString_part(ctx *parser.String_partContext) {
-	v, e := newValue(String, ctx.GetText())
-	if e == nil {
-		l.astContext.exprNode.rpn.append(v)
-	} else {
-		log.Fatal(e)
+	l.astContext.exprNode.rpn.append(value{String, unescape(ctx.GetText())})
+}
+
+// Placeholder options are parsed as sub-expressions preceding the placeholder
+// expression itself.
+func (l *wdlv1_1Listener) EnterExpression_placeholder_option(
+	ctx *parser.Expression_placeholder_optionContext,
+) {
+	e := newExpression(
+		ctx.GetStart().GetStart(),
+		ctx.GetStop().GetStop(),
+	)
+	e.setParent(l.astContext.exprNode)
+	l.astContext.exprNode.subExprs.push(e)
+	l.astContext.exprNode = e
+}
+
+func (l *wdlv1_1Listener) ExitExpression_placeholder_option(
+	ctx *parser.Expression_placeholder_optionContext,
+) {
+	l.astContext.exprNode = l.astContext.exprNode.getParent().(*expression)
+}
+
+// popPlaceholder pops a placeholder expression and its options off the
+// sub-expressions of the current expression. Only options are popped for a
+// placeholder missing its expression, like "~{}", for which nil is returned.
+func (l *wdlv1_1Listener) popPlaceholder(
+	expr parser.IExprContext,
+	options []parser.IExpression_placeholder_optionContext,
+) *expression {
+	if expr == nil { // missing in invalid WDL
+		for range options {
+			l.astContext.exprNode.subExprs.pop()
+		}
+		return nil
+	}
+	e := l.astContext.exprNode.subExprs.pop()
+	for i := len(options) - 1; i >= 0; i-- {
+		ctx := options[i].(*parser.Expression_placeholder_optionContext)
+		var name string
+		switch {
+		case ctx.BoolLiteral() != nil:
+			name = ctx.BoolLiteral().GetText()
+		case ctx.DEFAULTEQUAL() != nil:
+			name = "default"
+		case ctx.SEPEQUAL() != nil:
+			name = "sep"
+		}
+		opt := l.astContext.exprNode.subExprs.pop()
+		var v value
+		ok := false
+		if len(opt.rpn) == 1 {
+			v, ok = opt.rpn[0].(value)
+		}
+		if !ok {
+			if ctx.Wdl_string() == nil { // missing in invalid WDL
+				continue
+			}
+			// Keep an interpolated option string as written
+			v = value{String, strings.Trim(ctx.Wdl_string().GetText(), `"'`)}
+		}
+		if e.options == nil {
+			e.options = map[string]value{}
+		}
+		e.options[name] = v
 	}
+	return e
 }
+
 func (l *wdlv1_1Listener) ExitString_expr_part(
 	ctx *parser.String_expr_partContext,
 ) {
-	e := l.astContext.exprNode.subExprs.pop()
+	l.warnDollarPlaceholder(ctx.StringCommandStart(), ctx)
+	e := l.popPlaceholder(ctx.Expr(), ctx.AllExpression_placeholder_option())
+	if e == nil {
+		// Keep the string well formed without the missing placeholder.
+		l.astContext.exprNode.rpn.append(value{String, ""})
+		return
+	}
 	l.astContext.exprNode.rpn.append(e)
 	l.astContext.exprNode.rpn.append(WDLStr)
 }
@@ -290,12 +684,65 @@ func (l *wdlv1_1Listener) ExitExpression_group(
 
 func (l *wdlv1_1Listener) ExitUnarysigned(ctx *parser.UnarysignedContext) {
 	e := l.astContext.exprNode.subExprs.pop()
+	// Fold a signed number literal, like -3, into a single value.
+	if len(e.rpn) == 1 {
+		if v, ok := e.rpn[0].(value); ok {
+			switch g := v.govalue.(type) {
+			case int64:
+				if ctx.MINUS() != nil {
+					v.govalue = -g
+				}
+				l.astContext.exprNode.rpn.append(v)
+				return
+			case float64:
+				if ctx.MINUS() != nil {
+					v.govalue = -g
+				}
+				l.astContext.exprNode.rpn.append(v)
+				return
+			}
+		}
+	}
 	l.astContext.exprNode.rpn.append(e)
 	if ctx.MINUS() != nil {
 		l.astContext.exprNode.rpn.append(WDLNeg)
 	}
 }
 
+// popOperands moves the last n sub-expressions of the current expression into
+// its RPN in source order.
+func (l *wdlv1_1Listener) popOperands(n int) {
+	operands := make([]*expression, n)
+	for i := n - 1; i >= 0; i-- {
+		operands[i] = l.astContext.exprNode.subExprs.pop()
+	}
+	for _, e := range operands {
+		l.astContext.exprNode.rpn.append(e)
+	}
+}
+
+func (l *wdlv1_1Listener) ExitArray_literal(ctx *parser.Array_literalContext) {
+	n := len(ctx.AllExpr())
+	l.popOperands(n)
+	l.astContext.exprNode.rpn.append(nAryOp{WDLArray, n})
+}
+
+func (l *wdlv1_1Listener) ExitMap_literal(ctx *parser.Map_literalContext) {
+	n := len(ctx.AllExpr())
+	l.popOperands(n)
+	l.astContext.exprNode.rpn.append(nAryOp{WDLMap, n})
+}
+
+func (l *wdlv1_1Listener) ExitApply(ctx *parser.ApplyContext) {
+	n := len(ctx.AllExpr())
+	l.popOperands(n)
+	l.astContext.exprNode.rpn.append(function{ctx.Identifier().GetText(), n})
+}
+
+func (l *wdlv1_1Listener) ExitGet_name(ctx *parser.Get_nameContext) {
+	l.astContext.exprNode.rpn.append(member(ctx.Identifier().GetText()))
+}
+
 func (l *wdlv1_1Listener) ExitIfthenelse(ctx *parser.IfthenelseContext) {
 	e3 := l.astContext.exprNode.subExprs.pop()
 	e2 := l.astContext.exprNode.subExprs.pop()
@@ -1,6 +1,7 @@
 package wdlparser
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -39,6 +40,18 @@ func TestPrimitiveLiteral(t *testing.T) {
 			`version 1.1 workflow Test {input{String t="double quote string"}}`,
 			exprRPN{value{String, "double quote string"}},
 		},
+		{
+			`version 1.1 workflow Test {input{String t="line1\nline2\t\\"}}`,
+			exprRPN{value{String, "line1\nline2\t\\"}},
+		},
+		{
+			`version 1.1 workflow Test {input{String t="\""}}`,
+			exprRPN{value{String, `"`}},
+		},
+		{
+			`version 1.1 workflow Test {input{String t='\u00e9\x41\101\~\q'}}`,
+			exprRPN{value{String, "\u00e9AA~\\q"}},
+		},
 	}
 	for _, tc := range testCases {
 		result, err := Antlr4Parse(tc.wdl)
@@ -123,6 +136,45 @@ func TestExpressionPlaceholder(t *testing.T) {
 	}
 }
 
+func TestStringPlaceholderOption(t *testing.T) {
+	wdl := `version 1.1 workflow Test {input{String t="-f ~{sep=' -f ' files}"}}`
+	result, err := Antlr4Parse(wdl)
+	if err != nil {
+		t.Errorf("Found %d errors in %q, expect no errors", len(err), wdl)
+	}
+	want := exprRPN{
+		value{String, "-f "},
+		&expression{
+			genNode: genNode{start: 59, end: 63},
+			rpn:     exprRPN{newIdentifier("files", true)},
+			options: map[string]value{"sep": {String, " -f "}},
+		},
+		WDLStr,
+		value{String, ""},
+		WDLAdd,
+		WDLAdd,
+	}
+	v := *result.Workflow.Inputs[0].value
+	if diff := cmp.Diff(want, v, commonCmpopts...); diff != "" {
+		t.Errorf("unexpected string placeholder:\n%s", diff)
+	}
+}
+
+func TestPlaceholderOptionMissingString(t *testing.T) {
+	wdl := `version 1.1 workflow Test {input{String t="~{sep= files}"}}`
+	result, err := Antlr4Parse(wdl)
+	if len(err) != 1 {
+		t.Errorf("Found %d errors in %q, expect 1 error", len(err), wdl)
+	}
+	if len(result.Workflow.Inputs) != 1 {
+		t.Fatalf("input of %q should be kept", wdl)
+	}
+	refs := result.Workflow.Inputs[0].value.References()
+	if len(refs) != 1 || refs[0].initialName != "files" {
+		t.Errorf("placeholder of %q should refer to files", wdl)
+	}
+}
+
 func TestSinglePrimitiveExpression(t *testing.T) {
 	testCases := []struct {
 		wdl  string
@@ -130,10 +182,18 @@ func TestSinglePrimitiveExpression(t *testing.T) {
 	}{
 		{
 			"version 1.1 workflow Test {input{Int t=-3}}",
+			exprRPN{value{Int, int64(-3)}},
+		},
+		{
+			"version 1.1 workflow Test {input{Float t=+2.5}}",
+			exprRPN{value{Float, float64(2.5)}},
+		},
+		{
+			"version 1.1 workflow Test {input{Int t=-x}}",
 			exprRPN{
 				&expression{
 					genNode: genNode{start: 40, end: 40},
-					rpn:     exprRPN{value{Int, int64(3)}},
+					rpn:     exprRPN{newIdentifier("x", true)},
 				},
 				WDLNeg,
 			},
@@ -328,3 +388,131 @@ func TestExpression(t *testing.T) {
 		}
 	}
 }
+
+func TestStringValue(t *testing.T) {
+	testCases := []struct {
+		expr  string
+		want  string
+		isStr bool
+	}{
+		{`"4 GB"`, "4 GB", true},
+		{`"~{4} GB"`, "4 GB", true},
+		{`"a~{"b"}c~{'d'}"`, "abcd", true},
+		{`"~{size} GB"`, "", false},
+		{`"~{default='4' size} GB"`, "", false},
+		{"4", "", false},
+	}
+	for _, tc := range testCases {
+		wdl := "version 1.1 workflow Test {input{String t = " + tc.expr + "}}"
+		result, err := Antlr4Parse(wdl)
+		if err != nil {
+			t.Errorf("Found %d errors in %q, expect no errors", len(err), wdl)
+		}
+		s, ok := result.Workflow.Inputs[0].value.StringValue()
+		if s != tc.want || ok != tc.isStr {
+			t.Errorf(
+				"got string value %q, %v of %s, expect %q, %v",
+				s, ok, tc.expr, tc.want, tc.isStr,
+			)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	testCases := []struct {
+		expr string
+		want []string
+	}{
+		{"1 + 2", nil},
+		{"a + b * (c - 1)", []string{"a", "b", "c"}},
+		{`"~{prefix}.~{align.out.name}"`, []string{"prefix", "align"}},
+		{"if flag then [x, y] else length(z)", []string{"flag", "x", "y", "z"}},
+	}
+	for _, tc := range testCases {
+		wdl := "version 1.1 workflow Test {input{String t = " + tc.expr + "}}"
+		result, err := Antlr4Parse(wdl)
+		if err != nil {
+			t.Errorf("Found %d errors in %q, expect no errors", len(err), wdl)
+		}
+		var names []string
+		for _, ref := range result.Workflow.Inputs[0].value.References() {
+			names = append(names, ref.initialName)
+		}
+		if diff := cmp.Diff(tc.want, names); diff != "" {
+			t.Errorf("unexpected references of %s:\n%s", tc.expr, diff)
+		}
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	tighter := []WDLOpSym{
+		WDLNeg, WDLMul, WDLAdd, WDLEq, WDLAnd, WDLOr, WDLTernary,
+	}
+	for i := 1; i < len(tighter); i++ {
+		if tighter[i-1].Precedence() <= tighter[i].Precedence() {
+			t.Errorf(
+				"%s should bind tighter than %s", tighter[i-1], tighter[i],
+			)
+		}
+	}
+	for _, ops := range [][]WDLOpSym{
+		{WDLNeg, WDLNot},
+		{WDLMul, WDLDiv, WDLMod},
+		{WDLAdd, WDLSub},
+		{WDLEq, WDLNeq, WDLLt, WDLLte, WDLGt, WDLGte},
+	} {
+		for _, op := range ops[1:] {
+			if op.Precedence() != ops[0].Precedence() {
+				t.Errorf("%s should bind as tight as %s", op, ops[0])
+			}
+		}
+	}
+	if WDLArray.Precedence() <= WDLNeg.Precedence() {
+		t.Errorf("array literals should bind tighter than any operator")
+	}
+	if WDLSub.Associativity() != LeftAssociative {
+		t.Errorf("%s should be left associative", WDLSub)
+	}
+	if WDLNot.Associativity() != RightAssociative {
+		t.Errorf("%s should be right associative", WDLNot)
+	}
+}
+
+func TestValueString(t *testing.T) {
+	testCases := []struct {
+		expr string
+		want string
+	}{
+		{`3`, `Int(3)`},
+		{`-2.5`, `Float(-2.5)`},
+		{`1.0 * 2`, `Float(2.0)`},
+		{`"abc"`, `String("abc")`},
+		{`'a"b'`, `String("a\"b")`},
+		{`true`, `Boolean(true)`},
+		{`None`, `None`},
+		{`[1, 2]`, `Array[Int]([Int(1), Int(2)])`},
+		{`[]`, `Array[Any]([])`},
+	}
+	for _, tc := range testCases {
+		input := "version 1.1 workflow W { String s = " + tc.expr + " }"
+		result, errs := Antlr4Parse(input)
+		if errs != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(errs), input,
+			)
+			continue
+		}
+		v, err := result.Workflow.PrvtDecls[0].value.evaluate(nil)
+		if err != nil {
+			t.Errorf("failed to evaluate %q: %v", tc.expr, err)
+			continue
+		}
+		if got := v.String(); got != tc.want {
+			t.Errorf("%q should print as %q is %q", tc.expr, tc.want, got)
+		}
+	}
+	rpn := exprRPN{value{Int, int64(1)}, value{Int, int64(2)}, WDLAdd}
+	if got := fmt.Sprintf("%v", rpn); got != "[Int(1) Int(2) +]" {
+		t.Errorf("RPN should print as %q is %q", "[Int(1) Int(2) +]", got)
+	}
+}
@@ -0,0 +1,321 @@
+package wdlparser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+	parser "github.com/yunhailuo/wdlparser/pkg/antlr4_grammar/1_1"
+)
+
+const indent = "    "
+
+// wdlWriter builds WDL source text with blocks indented by level.
+type wdlWriter struct {
+	strings.Builder
+	level int
+}
+
+func (b *wdlWriter) line(format string, a ...interface{}) {
+	b.WriteString(strings.Repeat(indent, b.level))
+	fmt.Fprintf(b, format, a...)
+	b.WriteString("\n")
+}
+
+// comments writes leading comments of a node, if any.
+func (b *wdlWriter) comments(n node) {
+	for _, c := range n.LeadingComments() {
+		b.line("%s", c)
+	}
+}
+
+// open starts a block, like a section, separated from any previous sibling
+// by a blank line. The node of the block, if any, is given for its comments.
+func (b *wdlWriter) open(
+	first bool, n node, format string, a ...interface{},
+) {
+	if !first {
+		b.WriteString("\n")
+	}
+	if n != nil {
+		b.comments(n)
+	}
+	b.line(format+" {", a...)
+	b.level++
+}
+
+func (b *wdlWriter) close() {
+	b.level--
+	b.line("}")
+}
+
+func (b *wdlWriter) declarations(decls []*valueSpec) {
+	for _, decl := range decls {
+		b.comments(decl)
+		if decl.raw == "" {
+			b.line("%s %s", decl.typ, decl.name.initialName)
+		} else {
+			b.line("%s %s = %s", decl.typ, decl.name.initialName, decl.raw)
+		}
+	}
+}
+
+func (b *wdlWriter) section(first bool, name string, decls []*valueSpec) {
+	b.open(first, nil, name)
+	b.declarations(decls)
+	b.close()
+}
+
+func (b *wdlWriter) keyValues(first bool, name string, kvs []*valueSpec) {
+	b.open(first, nil, name)
+	for _, kv := range kvs {
+		b.comments(kv)
+		b.line("%s: %s", kv.name.initialName, kv.raw)
+	}
+	b.close()
+}
+
+// Format renders a parsed WDL document as consistently indented WDL source.
+// Values of declarations, call inputs, runtime and metadata are rendered as
+// they are written in the source while task commands are kept verbatim, so
+// formatting a formatted document doesn't change it. Leading comments of nodes
+// are kept but other comments are dropped, as listed by DroppedComments.
+func Format(wdl *WDL) (string, error) {
+	b := new(wdlWriter)
+	b.line("version %s", wdl.Version)
+
+	if len(wdl.Imports) > 0 {
+		b.WriteString("\n")
+	}
+	for _, is := range wdl.Imports {
+		b.comments(is)
+		if is.alias == "" {
+			b.line("import %q", is.uri.literal())
+		} else {
+			b.line("import %q as %s", is.uri.literal(), is.alias)
+		}
+		originals := make([]string, 0, len(is.importAliases))
+		for original := range is.importAliases {
+			originals = append(originals, original)
+		}
+		sort.Strings(originals)
+		b.level++
+		for _, original := range originals {
+			b.line("alias %s as %s", original, is.importAliases[original])
+		}
+		b.level--
+	}
+
+	for _, s := range wdl.Structs {
+		b.open(false, s, "struct %s", s.name.initialName)
+		b.declarations(s.Members)
+		b.close()
+	}
+
+	if wdl.Workflow != nil {
+		formatWorkflow(b, wdl.Workflow)
+	}
+
+	for _, t := range wdl.Tasks {
+		formatTask(b, t)
+	}
+
+	return b.String(), nil
+}
+
+func formatWorkflow(b *wdlWriter, w *Workflow) {
+	b.open(false, w, "workflow %s", w.name.initialName)
+	first := true
+	if len(w.Inputs) > 0 {
+		b.section(first, "input", w.Inputs)
+		first = false
+	}
+	first = formatBody(b, w.body(), first)
+	if len(w.Outputs) > 0 || w.HasOutputSection {
+		b.section(first, "output", w.Outputs)
+		first = false
+	}
+	if len(w.Meta) > 0 {
+		b.keyValues(first, "meta", w.Meta)
+		first = false
+	}
+	if len(w.ParameterMeta) > 0 {
+		b.keyValues(first, "parameter_meta", w.ParameterMeta)
+	}
+	b.close()
+}
+
+// formatBody writes declarations, calls, scatters and conditionals in a
+// workflow body. Consecutive declarations are grouped while others are
+// separated by blank lines. It returns false if anything is written.
+func formatBody(b *wdlWriter, nodes []node, first bool) bool {
+	prevDecl := false
+	for _, n := range nodes {
+		_, isDecl := n.(*valueSpec)
+		if !first && !(isDecl && prevDecl) {
+			b.WriteString("\n")
+		}
+		switch n := n.(type) {
+		case *valueSpec:
+			b.declarations([]*valueSpec{n})
+		case *Call:
+			formatCall(b, n)
+		case *Scatter:
+			b.open(true, n, "scatter (%s in %s)", n.Variable, n.raw)
+			formatBody(b, n.Body, true)
+			b.close()
+		case *Conditional:
+			b.open(true, n, "if (%s)", n.raw)
+			formatBody(b, n.Body, true)
+			b.close()
+		}
+		first = false
+		prevDecl = isDecl
+	}
+	return first
+}
+
+func formatCall(b *wdlWriter, c *Call) {
+	header := "call " + c.name.initialName
+	if c.alias != "" {
+		header += " as " + c.alias
+	}
+	for _, after := range c.After {
+		header += " after " + after
+	}
+	if len(c.Inputs) == 0 {
+		b.comments(c)
+		b.line("%s", header)
+		return
+	}
+	b.open(true, c, "%s", header)
+	b.line("input:")
+	b.level++
+	for _, input := range c.Inputs {
+		b.comments(input)
+		if input.raw == "" {
+			b.line("%s,", input.name.initialName)
+		} else {
+			b.line("%s = %s,", input.name.initialName, input.raw)
+		}
+	}
+	b.level--
+	b.close()
+}
+
+// A taskPart is a section of a task, or one of its private declarations, to be
+// formatted where it starts. Parts starting at the same position, like those
+// of a task built without positions, are ordered by rank.
+type taskPart struct {
+	start, rank int
+	decl        *valueSpec // private declaration, or nil for a section
+	write       func(first bool)
+}
+
+// formatTask renders a task with its declarations and command in source order,
+// so that private declarations stay where they are written, like after the
+// command or between sections.
+func formatTask(b *wdlWriter, t *Task) {
+	b.open(false, t, "task %s", t.name.initialName)
+	var parts []taskPart
+	for _, decl := range t.DeclarationsInSourceOrder() {
+		switch {
+		case len(t.Inputs) > 0 && decl == t.Inputs[0]:
+			parts = append(parts, taskPart{decl.getStart(), 0, nil,
+				func(first bool) { b.section(first, "input", t.Inputs) },
+			})
+		case len(t.Outputs) > 0 && decl == t.Outputs[0]:
+			parts = append(parts, taskPart{decl.getStart(), 3, nil,
+				func(first bool) { b.section(first, "output", t.Outputs) },
+			})
+		case containsValueSpec(t.PrvtDecls, decl):
+			parts = append(parts, taskPart{decl.getStart(), 1, decl, nil})
+		}
+	}
+	if t.CommandStyle != 0 {
+		parts = append(parts, taskPart{t.commandStart, 2, nil,
+			func(first bool) {
+				if !first {
+					b.WriteString("\n")
+				}
+				if t.CommandStyle == BraceCommand {
+					b.line("command {%s}", t.CommandString())
+				} else {
+					b.line("command <<<%s>>>", t.CommandString())
+				}
+			},
+		})
+	}
+	for _, section := range []struct {
+		rank int
+		name string
+		kvs  []*valueSpec
+	}{
+		{4, "runtime", t.Runtime},
+		{5, "meta", t.Meta},
+		{6, "parameter_meta", t.ParameterMeta},
+	} {
+		section := section
+		if len(section.kvs) == 0 {
+			continue
+		}
+		parts = append(parts, taskPart{section.kvs[0].getStart(), section.rank,
+			nil, func(first bool) {
+				b.keyValues(first, section.name, section.kvs)
+			},
+		})
+	}
+	sort.SliceStable(parts, func(i, j int) bool {
+		if parts[i].start != parts[j].start {
+			return parts[i].start < parts[j].start
+		}
+		return parts[i].rank < parts[j].rank
+	})
+	for i, part := range parts {
+		if part.decl == nil {
+			part.write(i == 0)
+			continue
+		}
+		if i > 0 && parts[i-1].decl == nil {
+			b.WriteString("\n") // a run of private declarations starts
+		}
+		b.declarations([]*valueSpec{part.decl})
+	}
+	b.close()
+}
+
+// DroppedComments returns comments of a parsed WDL document missing from its
+// formatted source text, as Format returns it, in source order. Those are the
+// comments Format drops, like comments trailing a line or ending a section.
+func DroppedComments(wdl *WDL, formatted string) []string {
+	kept := map[string]int{}
+	for _, c := range comments(formatted) {
+		kept[c]++
+	}
+	var dropped []string
+	for _, c := range comments(wdl.source) {
+		if kept[c] > 0 {
+			kept[c]--
+			continue
+		}
+		dropped = append(dropped, c)
+	}
+	return dropped
+}
+
+// comments returns text of every comment in WDL source text.
+func comments(src string) []string {
+	lexer := parser.NewWdlV1_1Lexer(antlr.NewInputStream(src))
+	lexer.RemoveErrorListeners()
+	var texts []string
+	for {
+		token := lexer.NextToken()
+		if token.GetTokenType() == antlr.TokenEOF {
+			return texts
+		}
+		if token.GetChannel() == parser.WdlV1_1LexerCOMMENTS {
+			texts = append(texts, token.GetText())
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package wdlparser
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFormat(t *testing.T) {
+	wdl := `version 1.1
+import "lib.wdl"   as lib alias B as C   alias A as D
+struct Pair2 {Int left
+   Int right}
+workflow   Hello {
+  input { String name   = "World" File? f }
+  call lib.Greet as greet after other { input: name= name, f }
+  call Bye
+  output{String out=greet.out}
+  meta {author: "Yunhai Luo"}
+}
+task Bye {
+command <<<
+  echo "Bye ~{ name }"
+>>>
+runtime {container:"ubuntu:latest"}
+}
+`
+	expected := `version 1.1
+
+import "lib.wdl" as lib
+    alias A as D
+    alias B as C
+
+struct Pair2 {
+    Int left
+    Int right
+}
+
+workflow Hello {
+    input {
+        String name = "World"
+        File? f
+    }
+
+    call lib.Greet as greet after other {
+        input:
+            name = name,
+            f,
+    }
+
+    call Bye
+
+    output {
+        String out = greet.out
+    }
+
+    meta {
+        author: "Yunhai Luo"
+    }
+}
+
+task Bye {
+    command <<<
+  echo "Bye ~{ name }"
+>>>
+
+    runtime {
+        container: "ubuntu:latest"
+    }
+}
+`
+	result, errs := Antlr4Parse(wdl)
+	if errs != nil {
+		t.Errorf("Found %d errors in %q, expect no errors", len(errs), wdl)
+	}
+	formatted, err := Format(result)
+	if err != nil {
+		t.Fatalf("failed to format %q: %v", wdl, err)
+	}
+	if diff := cmp.Diff(expected, formatted); diff != "" {
+		t.Errorf("unexpected formatted WDL:\n%s", diff)
+	}
+}
+
+func TestFormatDeclarationOrder(t *testing.T) {
+	inputPath := "testdata/task_declaration_order.wdl"
+	expected := `version 1.1
+
+task Order {
+    String before_input = "a"
+
+    input {
+        String name
+    }
+
+    String before_command = name
+
+    command <<<
+        echo ~{before_command}
+    >>>
+
+    String after_command = before_input
+
+    output {
+        String out = after_command
+    }
+
+    String last = "z"
+}
+`
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	formatted, err := Format(result)
+	if err != nil {
+		t.Fatalf("failed to format %q: %v", inputPath, err)
+	}
+	if diff := cmp.Diff(expected, formatted); diff != "" {
+		t.Errorf("unexpected formatted WDL:\n%s", diff)
+	}
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	inputPaths, err := filepath.Glob("testdata/*.wdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, inputPath := range inputPaths {
+		result, errs := Antlr4Parse(inputPath)
+		if errs != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(errs), inputPath,
+			)
+		}
+		once, err := Format(result)
+		if err != nil {
+			t.Fatalf("failed to format %q: %v", inputPath, err)
+		}
+		result, errs = Antlr4Parse(once)
+		if errs != nil {
+			t.Errorf(
+				"Found %d errors in formatted %q, expect no errors",
+				len(errs), inputPath,
+			)
+		}
+		twice, err := Format(result)
+		if err != nil {
+			t.Fatalf("failed to format formatted %q: %v", inputPath, err)
+		}
+		if diff := cmp.Diff(once, twice); diff != "" {
+			t.Errorf("formatting %q twice changes it:\n%s", inputPath, diff)
+		}
+	}
+}
+
+func TestFormatBlocks(t *testing.T) {
+	wdl := "version 1.1 workflow Test {scatter (i in [1, 2]) {if (i > 1) " +
+		"{call T} Int j = i}}"
+	expected := `version 1.1
+
+workflow Test {
+    scatter (i in [1, 2]) {
+        if (i > 1) {
+            call T
+        }
+
+        Int j = i
+    }
+}
+`
+	result, errs := Antlr4Parse(wdl)
+	if errs != nil {
+		t.Errorf("Found %d errors in %q, expect no errors", len(errs), wdl)
+	}
+	formatted, err := Format(result)
+	if err != nil {
+		t.Fatalf("failed to format %q: %v", wdl, err)
+	}
+	if diff := cmp.Diff(expected, formatted); diff != "" {
+		t.Errorf("unexpected formatted WDL:\n%s", diff)
+	}
+}
+
+func TestFormatEmptyOutputSection(t *testing.T) {
+	result, errs := Antlr4Parse("version 1.1 workflow W { output {} }")
+	if errs != nil {
+		t.Errorf("Found %d errors, expect no errors", len(errs))
+	}
+	formatted, err := Format(result)
+	if err != nil {
+		t.Fatalf("failed to format: %v", err)
+	}
+	expected := `version 1.1
+
+workflow W {
+    output {
+    }
+}
+`
+	if diff := cmp.Diff(expected, formatted); diff != "" {
+		t.Errorf("unexpected formatted WDL:\n%s", diff)
+	}
+}
+
+func TestDroppedComments(t *testing.T) {
+	inputPath := "testdata/comment.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	formatted, err := Format(result)
+	if err != nil {
+		t.Fatalf("failed to format %q: %v", inputPath, err)
+	}
+	expected := []string{"# not leading", "# Dangling comment"}
+	if diff := cmp.Diff(
+		expected, DroppedComments(result, formatted),
+	); diff != "" {
+		t.Errorf("unexpected dropped comments:\n%s", diff)
+	}
+}
@@ -0,0 +1,247 @@
+package wdlparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dependencies returns calls a call depends on, which are the ones it's after
+// and the ones whose outputs it refers to, in the order they're found.
+func (w *Workflow) dependencies(c *Call) []*Call {
+	calls := map[string]*Call{}
+	for _, other := range w.Calls {
+		calls[other.callName()] = other
+	}
+	var deps []*Call
+	seen := map[*Call]bool{}
+	add := func(name string) {
+		if dep, ok := calls[name]; ok && dep != c && !seen[dep] {
+			seen[dep] = true
+			deps = append(deps, dep)
+		}
+	}
+	for _, after := range c.After {
+		add(after)
+	}
+	for _, name := range w.referredCalls(c) {
+		add(name)
+	}
+	return deps
+}
+
+// referredCalls returns names of calls whose outputs a call refers to, in the
+// order they're found. Besides its inputs, references of private declarations
+// they refer to are followed, transitively, as are references of collections
+// and conditions of scatters and conditionals enclosing the call or those
+// declarations.
+func (w *Workflow) referredCalls(c *Call) []string {
+	calls := map[string]bool{}
+	for _, other := range w.Calls {
+		calls[other.callName()] = true
+	}
+	decls := map[string]*valueSpec{}
+	for _, decl := range w.PrvtDecls {
+		decls[decl.name.initialName] = decl
+	}
+	var names []string
+	seen := map[string]bool{}
+	var follow func(rpn *exprRPN)
+	followBlocks := func(n node) {
+		for p := n.getParent(); p != nil; p = p.getParent() {
+			switch p := p.(type) {
+			case *Scatter:
+				follow(p.Collection)
+			case *Conditional:
+				follow(p.Condition)
+			}
+		}
+	}
+	follow = func(rpn *exprRPN) {
+		if rpn == nil {
+			return
+		}
+		for _, ref := range rpn.References() {
+			name := ref.initialName
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if calls[name] {
+				names = append(names, name)
+			} else if decl, ok := decls[name]; ok {
+				follow(decl.value)
+				followBlocks(decl)
+			}
+		}
+	}
+	for _, input := range c.Inputs {
+		follow(input.value)
+	}
+	followBlocks(c)
+	return names
+}
+
+// ExecutionOrder returns calls of a workflow in an order where every call
+// comes after the calls it's declared to be after and the calls whose outputs
+// it refers to, even through private declarations. Independent calls keep
+// their order in the source. An error naming the calls in a cycle is returned
+// if there's no such order.
+func (w *Workflow) ExecutionOrder() ([]*Call, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[*Call]int{}
+	var order, path []*Call
+	var visit func(c *Call) error
+	visit = func(c *Call) error {
+		state[c] = visiting
+		path = append(path, c)
+		for _, dep := range w.dependencies(c) {
+			switch state[dep] {
+			case visiting:
+				start := len(path) - 1
+				for path[start] != dep {
+					start--
+				}
+				var names []string
+				for _, call := range path[start:] {
+					names = append(names, call.callName())
+				}
+				names = append(names, dep.callName())
+				return fmt.Errorf(
+					"calls depend on each other: %s",
+					strings.Join(names, " -> "),
+				)
+			case 0:
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[c] = done
+		order = append(order, c)
+		return nil
+	}
+	for _, c := range w.Calls {
+		if state[c] == 0 {
+			if err := visit(c); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return order, nil
+}
+
+// fanOut returns how many times calls in a node run for every run of the
+// workflow, which is the product of sizes of the scatters enclosing it. The
+// size of a scatter is known only if it's over an array literal, whose
+// elements may be any expressions, like arrays or function calls.
+func fanOut(n node) (int, error) {
+	width := 1
+	for p := n.getParent(); p != nil; p = p.getParent() {
+		s, ok := p.(*Scatter)
+		if !ok {
+			continue
+		}
+		var op nAryOp
+		if s.Collection != nil && len(*s.Collection) > 0 {
+			// The last operator of an RPN is the outermost one.
+			rpn := *s.Collection
+			op, _ = rpn[len(rpn)-1].(nAryOp)
+		}
+		if op.op != WDLArray {
+			return 0, fmt.Errorf("scatter over %s has an unknown size", s.raw)
+		}
+		width *= op.n
+	}
+	return width, nil
+}
+
+// MaxParallelism estimates the largest number of calls of a workflow which
+// can run at the same time. Calls are run in stages, where a call is in the
+// stage after the last of the calls it depends on, and a call in scatters
+// counts as many times as the scatters run it. An error is returned if calls
+// depend on each other or if a call is in a scatter of unknown size, which is
+// one not over an array literal.
+func (w *Workflow) MaxParallelism() (int, error) {
+	order, err := w.ExecutionOrder()
+	if err != nil {
+		return 0, err
+	}
+	stages := map[*Call]int{}
+	widths := map[int]int{}
+	max := 0
+	for _, c := range order {
+		for _, dep := range w.dependencies(c) {
+			if stages[dep]+1 > stages[c] {
+				stages[c] = stages[dep] + 1
+			}
+		}
+		n, err := fanOut(c)
+		if err != nil {
+			return 0, err
+		}
+		widths[stages[c]] += n
+		if widths[stages[c]] > max {
+			max = widths[stages[c]]
+		}
+	}
+	return max, nil
+}
+
+// ToDOT renders calls of a workflow as a Graphviz digraph in the DOT
+// language. Calls are nodes named by their aliases or callees, and scatters
+// and conditionals are clusters of the calls in them. An edge goes from a call
+// to every call depending on it, dashed if the dependency is only declared
+// with after.
+func (w *Workflow) ToDOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", w.name.initialName)
+	clusters := 0
+	var write func(nodes []node, level int)
+	cluster := func(label string, body []node, level int) {
+		indent := strings.Repeat("    ", level)
+		clusters++
+		fmt.Fprintf(&b, "%ssubgraph \"cluster_%d\" {\n", indent, clusters)
+		fmt.Fprintf(&b, "%s    label = %q;\n", indent, label)
+		write(body, level+1)
+		fmt.Fprintf(&b, "%s}\n", indent)
+	}
+	write = func(nodes []node, level int) {
+		for _, n := range nodes {
+			switch n := n.(type) {
+			case *Call:
+				fmt.Fprintf(
+					&b, "%s%q;\n", strings.Repeat("    ", level), n.callName(),
+				)
+			case *Scatter:
+				label := "scatter (" + n.Variable + " in " + n.raw + ")"
+				cluster(label, n.Body, level)
+			case *Conditional:
+				cluster("if ("+n.raw+")", n.Body, level)
+			}
+		}
+	}
+	write(w.body(), 1)
+	for _, c := range w.Calls {
+		after := map[string]bool{}
+		for _, name := range c.After {
+			after[name] = true
+		}
+		referred := map[string]bool{}
+		for _, name := range w.referredCalls(c) {
+			referred[name] = true
+		}
+		for _, dep := range w.dependencies(c) {
+			fmt.Fprintf(&b, "    %q -> %q", dep.callName(), c.callName())
+			if after[dep.callName()] && !referred[dep.callName()] {
+				b.WriteString(" [style=dashed]")
+			}
+			b.WriteString(";\n")
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,200 @@
+package wdlparser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExecutionOrder(t *testing.T) {
+	inputPath := "testdata/workflow_call_order.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	order, err := result.Workflow.ExecutionOrder()
+	if err != nil {
+		t.Fatalf("failed to order calls: %v", err)
+	}
+	var names []string
+	for _, c := range order {
+		names = append(names, c.callName())
+	}
+	expected := []string{"Align", "summarize", "Report", "Cleanup"}
+	if diff := cmp.Diff(expected, names); diff != "" {
+		t.Errorf("unexpected execution order:\n%s", diff)
+	}
+
+	inputPath = "testdata/workflow_call_cycle.wdl"
+	result, errs = Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	_, err = result.Workflow.ExecutionOrder()
+	expectedErr := "calls depend on each other: Align -> Index -> Sort -> Align"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("error should be %q is %v", expectedErr, err)
+	}
+}
+
+func TestExecutionOrderAfterMany(t *testing.T) {
+	inputPath := "testdata/workflow_call_after_many.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	merge := result.Workflow.Calls[0]
+	if diff := cmp.Diff([]string{"Left", "Right"}, merge.After); diff != "" {
+		t.Errorf("unexpected dependencies of %q:\n%s", "Merge", diff)
+	}
+	order, err := result.Workflow.ExecutionOrder()
+	if err != nil {
+		t.Fatalf("failed to order calls: %v", err)
+	}
+	var names []string
+	for _, c := range order {
+		names = append(names, c.callName())
+	}
+	expected := []string{"Left", "Right", "Merge"}
+	if diff := cmp.Diff(expected, names); diff != "" {
+		t.Errorf("unexpected execution order:\n%s", diff)
+	}
+}
+
+func TestMaxParallelism(t *testing.T) {
+	testCases := []struct {
+		body string
+		want int
+		err  string
+	}{
+		{"call T as a call T as b", 2, ""},
+		{"call T as a call T as b { input: x = a.out }", 1, ""},
+		{
+			"call T as a scatter (i in [1, 2, 3]) { call T as b }",
+			4,
+			"",
+		},
+		{
+			"call T as a scatter (i in [1, 2]) " +
+				"{ call T as b { input: x = a.out } }",
+			2,
+			"",
+		},
+		{
+			"Array[Int] xs = [1] scatter (i in xs) { call T }",
+			0,
+			"scatter over xs has an unknown size",
+		},
+		{
+			"scatter (p in [[1, 2], [3]]) { call T }",
+			2,
+			"",
+		},
+		{
+			`scatter (s in ["a", "b" + x, length(xs)]) { call T }`,
+			3,
+			"",
+		},
+		{
+			"scatter (i in if b then [1] else [2, 3]) { call T }",
+			0,
+			"scatter over if b then [1] else [2, 3] has an unknown size",
+		},
+	}
+	for _, tc := range testCases {
+		input := "version 1.1 workflow W {" + tc.body + "}"
+		result, errs := Antlr4Parse(input)
+		if errs != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(errs), input,
+			)
+			continue
+		}
+		got, err := result.Workflow.MaxParallelism()
+		if err != nil && err.Error() != tc.err ||
+			err == nil && tc.err != "" {
+			t.Errorf("error should be %q is %v", tc.err, err)
+		}
+		if got != tc.want {
+			t.Errorf(
+				"max parallelism of %q is %d, expect %d",
+				tc.body, got, tc.want,
+			)
+		}
+	}
+}
+
+func TestToDOT(t *testing.T) {
+	inputPath := "testdata/workflow_dot.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+	expected := `digraph "Dot" {
+    "Prepare";
+    subgraph "cluster_1" {
+        label = "scatter (sample in samples)";
+        "Align";
+    }
+    subgraph "cluster_2" {
+        label = "if (report)";
+        "Report";
+    }
+    "Prepare" -> "Align";
+    "Prepare" -> "Report" [style=dashed];
+    "Align" -> "Report";
+}
+`
+	if diff := cmp.Diff(expected, result.Workflow.ToDOT()); diff != "" {
+		t.Errorf("unexpected dot graph:\n%s", diff)
+	}
+}
+
+func TestFanOutEmptyCollection(t *testing.T) {
+	for _, collection := range []*exprRPN{nil, {}} {
+		s := NewScatter(0, 0, nil, "i")
+		s.Collection = collection
+		if _, err := fanOut(NewCall(0, 0, s, "T")); err == nil {
+			t.Errorf("expect an error for a scatter over %v", collection)
+		}
+	}
+}
+
+func TestExecutionOrderThroughDeclarations(t *testing.T) {
+	inputPath := "testdata/workflow_call_order_decls.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	order, err := result.Workflow.ExecutionOrder()
+	if err != nil {
+		t.Fatalf("failed to order calls: %v", err)
+	}
+	var names []string
+	for _, c := range order {
+		names = append(names, c.callName())
+	}
+	expected := []string{"Count", "Report", "Split", "Process", "Notify"}
+	if diff := cmp.Diff(expected, names); diff != "" {
+		t.Errorf("unexpected execution order:\n%s", diff)
+	}
+	dot := result.Workflow.ToDOT()
+	for _, edge := range []string{
+		`"Count" -> "Report";`, `"Split" -> "Process";`, `"Count" -> "Notify";`,
+	} {
+		if !strings.Contains(dot, edge) {
+			t.Errorf("dot graph should have edge %s:\n%s", edge, dot)
+		}
+	}
+}
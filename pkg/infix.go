@@ -0,0 +1,181 @@
+package wdlparser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An infix is an operand being converted from RPN to infix source.
+type infix struct {
+	text string
+	prec int     // precedence of the outermost operator in text
+	str  *string // content of an interpolated string, if it's one
+	opts string  // placeholder options of a sub-expression, if any
+}
+
+// wrap returns the text of an operand, parenthesized if its outermost
+// operator binds looser than prec.
+func (o infix) wrap(prec int) string {
+	if o.prec < prec {
+		return "(" + o.text + ")"
+	}
+	return o.text
+}
+
+// escape escapes characters in the content of a string literal as WDL
+// requires. It undoes unescape.
+func escape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`,
+		"~{", `\~{`, "${", `\${`,
+	).Replace(s)
+}
+
+// interpolated returns a string operand of a given content.
+func interpolated(content string) infix {
+	return infix{text: `"` + content + `"`, prec: primaryPrecedence, str: &content}
+}
+
+func literalInfix(v value) infix {
+	const primary = primaryPrecedence
+	switch g := v.govalue.(type) {
+	case nil:
+		return infix{text: "None", prec: primary}
+	case string:
+		return interpolated(escape(g))
+	case float64:
+		text := strconv.FormatFloat(g, 'f', -1, 64)
+		if !strings.ContainsAny(text, ".eE") {
+			text += ".0"
+		}
+		return infix{text: text, prec: primary}
+	}
+	return infix{text: fmt.Sprint(v.govalue), prec: primary}
+}
+
+// String converts an RPN back to infix WDL source, parenthesized only where
+// operator precedence requires. Interpolated strings are rendered as strings
+// with placeholders, so the text may differ from the source but means the
+// same.
+func (e *exprRPN) String() string {
+	if e == nil {
+		return ""
+	}
+	return e.infix().text
+}
+
+// infix converts an RPN to an infix operand.
+func (e *exprRPN) infix() infix {
+	const primary = primaryPrecedence
+	var stack []infix
+	pop := func(n int) []infix {
+		if len(stack) < n {
+			operands := make([]infix, n)
+			copy(operands[n-len(stack):], stack)
+			stack = nil
+			return operands
+		}
+		operands := stack[len(stack)-n:]
+		stack = stack[:len(stack)-n]
+		return operands
+	}
+	join := func(operands []infix, sep string) string {
+		texts := make([]string, 0, len(operands))
+		for _, o := range operands {
+			texts = append(texts, o.text)
+		}
+		return strings.Join(texts, sep)
+	}
+	for _, elem := range *e {
+		var o infix
+		switch elem := elem.(type) {
+		case value:
+			o = literalInfix(elem)
+		case *identifier:
+			o = infix{text: elem.initialName, prec: primary}
+		case *expression:
+			o = elem.rpn.infix()
+			names := make([]string, 0, len(elem.options))
+			for name := range elem.options {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				o.opts += fmt.Sprintf(
+					"%s=%s ", name, literalInfix(elem.options[name]).text,
+				)
+			}
+		case member:
+			o = infix{
+				text: pop(1)[0].wrap(primary) + "." + string(elem),
+				prec: primary,
+			}
+		case function:
+			o = infix{
+				text: elem.name + "(" + join(pop(elem.n), ", ") + ")",
+				prec: primary,
+			}
+		case nAryOp:
+			operands := pop(elem.n)
+			if elem.op == WDLArray {
+				o = infix{text: "[" + join(operands, ", ") + "]", prec: primary}
+				break
+			}
+			var entries []string
+			for i := 0; i+1 < len(operands); i += 2 {
+				entries = append(
+					entries, operands[i].text+": "+operands[i+1].text,
+				)
+			}
+			o = infix{
+				text: "{" + strings.Join(entries, ", ") + "}", prec: primary,
+			}
+		case WDLOpSym:
+			prec := elem.Precedence()
+			switch elem {
+			case WDLStr:
+				operand := pop(1)[0]
+				o = interpolated("~{" + operand.opts + operand.text + "}")
+			case WDLNeg:
+				o = infix{text: "-" + pop(1)[0].wrap(prec), prec: prec}
+			case WDLNot:
+				o = infix{text: "!" + pop(1)[0].wrap(prec), prec: prec}
+			case WDLTernary:
+				operands := pop(3)
+				o = infix{
+					text: "if " + operands[0].text +
+						" then " + operands[1].text +
+						" else " + operands[2].text,
+					prec: prec,
+				}
+			default:
+				operands := pop(2)
+				a, b := operands[0], operands[1]
+				if elem == WDLAdd && a.str != nil && b.str != nil {
+					o = interpolated(*a.str + *b.str)
+					break
+				}
+				// An operand of the same precedence on the side the
+				// operator doesn't associate to is parenthesized.
+				left, right := prec, prec+1
+				if elem.Associativity() == RightAssociative {
+					left, right = prec+1, prec
+				}
+				o = infix{
+					text: a.wrap(left) + " " + string(elem) + " " +
+						b.wrap(right),
+					prec: prec,
+				}
+			}
+		default:
+			o = infix{text: fmt.Sprint(elem), prec: primary}
+		}
+		stack = append(stack, o)
+	}
+	if len(stack) == 1 {
+		return stack[0]
+	}
+	return infix{text: join(stack, " "), prec: primary}
+}
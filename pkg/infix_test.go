@@ -0,0 +1,36 @@
+package wdlparser
+
+import "testing"
+
+func TestExprRPNString(t *testing.T) {
+	testCases := []struct {
+		expr string
+		want string
+	}{
+		{`1 - 5 * 2`, `1 - 5 * 2`},
+		{`(1 - 5) * 2`, `(1 - 5) * 2`},
+		{`1 - (5 - 2)`, `1 - (5 - 2)`},
+		{`(1 - 5) - 2`, `1 - 5 - 2`},
+		{`(a || b) && !c`, `(a || b) && !c`},
+		{`-(1 + 2)`, `-(1 + 2)`},
+		{`if a then 1 else 2.0`, `if a then 1 else 2.0`},
+		{`x.y + length(xs)`, `x.y + length(xs)`},
+		{`{"a": 1, "b": 2}`, `{"a": 1, "b": 2}`},
+		{`"a~{x}b~{sep=',' ys}c"`, `"a~{x}b~{sep="," ys}c"`},
+		{`None`, `None`},
+	}
+	for _, tc := range testCases {
+		input := "version 1.1 workflow W { String s = " + tc.expr + " }"
+		result, errs := Antlr4Parse(input)
+		if errs != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(errs), input,
+			)
+			continue
+		}
+		got := result.Workflow.PrvtDecls[0].value.String()
+		if got != tc.want {
+			t.Errorf("%q should print as %q is %q", tc.expr, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,503 @@
+package wdlparser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A Diagnostic describes a problem found in a parsed WDL document by a lint
+// rule. Start and end are 0-based character positions of the offending node.
+// Like those of syntax errors, Code is stable and Severity tells how serious
+// the problem is, which only depend on the rule.
+type Diagnostic struct {
+	Rule     string   `json:"rule"`
+	Start    int      `json:"start"`
+	End      int      `json:"end"`
+	Msg      string   `json:"msg"`
+	Code     string   `json:"code"`
+	Severity Severity `json:"severity"`
+}
+
+func newDiagnostic(rule string, n node, msg string) Diagnostic {
+	kind := diagnosticKinds[rule]
+	return Diagnostic{
+		Rule:     rule,
+		Start:    n.getStart(),
+		End:      n.getEnd(),
+		Msg:      msg,
+		Code:     kind.code,
+		Severity: kind.severity,
+	}
+}
+
+// A diagnosticKind is the code and severity of diagnostics of a rule.
+type diagnosticKind struct {
+	code     string
+	severity Severity
+}
+
+// diagnosticKinds are kinds of diagnostics by rule. Codes of diagnostics are
+// numbered from WDL101, after those of syntax errors, except that deprecated
+// constructs share their code with the syntax errors reported on them under
+// WarningsAsErrors.
+var diagnosticKinds = map[string]diagnosticKind{
+	AbsolutePathDefault:    {"WDL101", SeverityWarning},
+	CallNameCollision:      {"WDL102", SeverityError},
+	DynamicContainer:       {"WDL103", SeverityWarning},
+	InconsistentRuntimeKey: {"WDL104", SeverityWarning},
+	MissingCallInput:       {"WDL105", SeverityError},
+	NamespaceShadowsTask:   {"WDL106", SeverityWarning},
+	RequireStrictMode:      {"WDL107", SeverityInfo},
+	UnknownCallInput:       {"WDL108", SeverityError},
+	UnknownImportAlias:     {"WDL109", SeverityError},
+	UnknownNamespace:       {"WDL110", SeverityError},
+	UnknownType:            {"WDL111", SeverityError},
+	DuplicateName:          {"WDL112", SeverityError},
+	UnknownParameterMeta:   {"WDL113", SeverityWarning},
+	RecursiveStruct:        {"WDL114", SeverityError},
+	MixedTypeLiteral:       {"WDL115", SeverityError},
+	TypeMismatch:           {"WDL116", SeverityError},
+	Deprecated:             {CodeDeprecated, SeverityWarning},
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d %s: %s", d.Start, d.End, d.Rule, d.Msg)
+}
+
+func (d Diagnostic) Error() string { return d.String() }
+
+// Lint rule names
+const (
+	AbsolutePathDefault    = "AbsolutePathDefault"
+	CallNameCollision      = "CallNameCollision"
+	DynamicContainer       = "DynamicContainer"
+	InconsistentRuntimeKey = "InconsistentRuntimeKey"
+	MissingCallInput       = "MissingCallInput"
+	NamespaceShadowsTask   = "NamespaceShadowsTask"
+	RequireStrictMode      = "RequireStrictMode"
+	UnknownCallInput       = "UnknownCallInput"
+	UnknownImportAlias     = "UnknownImportAlias"
+	UnknownNamespace       = "UnknownNamespace"
+	UnknownType            = "UnknownType"
+)
+
+type lintRule func(w *WDL) []Diagnostic
+
+var lintRules = map[string]lintRule{
+	AbsolutePathDefault:    lintAbsolutePathDefault,
+	CallNameCollision:      lintCallNameCollision,
+	DynamicContainer:       lintDynamicContainer,
+	InconsistentRuntimeKey: lintInconsistentRuntimeKey,
+	MissingCallInput:       lintMissingCallInput,
+	NamespaceShadowsTask:   lintNamespaceShadowsTask,
+	RequireStrictMode:      lintRequireStrictMode,
+	UnknownCallInput:       lintUnknownCallInput,
+	UnknownImportAlias:     lintUnknownImportAlias,
+	UnknownNamespace:       lintUnknownNamespace,
+	UnknownType:            lintUnknownType,
+}
+
+// optInRules are lint rules on matters of taste, which are only checked when
+// named.
+var optInRules = map[string]bool{
+	RequireStrictMode: true,
+}
+
+// Lint checks a parsed WDL document against the named lint rules, or all lint
+// rules but opt-in ones, like RequireStrictMode, if no rule is given.
+func Lint(w *WDL, rules ...string) []Diagnostic {
+	if len(rules) == 0 {
+		for rule := range lintRules {
+			if !optInRules[rule] {
+				rules = append(rules, rule)
+			}
+		}
+		sort.Strings(rules)
+	}
+	var diags []Diagnostic
+	for _, rule := range rules {
+		if check, ok := lintRules[rule]; ok {
+			diags = append(diags, check(w)...)
+		}
+	}
+	return diags
+}
+
+// Summarize counts diagnostics by rule.
+func Summarize(diags []Diagnostic) map[string]int {
+	counts := map[string]int{}
+	for _, d := range diags {
+		counts[d.Rule]++
+	}
+	return counts
+}
+
+// declarations lists all declarations in workflow and tasks of a document.
+func (w *WDL) declarations() []*valueSpec {
+	var decls []*valueSpec
+	if w.Workflow != nil {
+		decls = append(decls, w.Workflow.Inputs...)
+		decls = append(decls, w.Workflow.PrvtDecls...)
+		decls = append(decls, w.Workflow.Outputs...)
+	}
+	for _, t := range w.Tasks {
+		decls = append(decls, t.Inputs...)
+		decls = append(decls, t.PrvtDecls...)
+		decls = append(decls, t.Outputs...)
+	}
+	return decls
+}
+
+var windowsAbsPath = regexp.MustCompile(`^[a-zA-Z]:[\\/]`)
+
+// lintAbsolutePathDefault flags String or File declarations bound to an
+// absolute local path, which is unlikely to exist on another machine.
+func lintAbsolutePathDefault(w *WDL) []Diagnostic {
+	var diags []Diagnostic
+	for _, decl := range w.declarations() {
+		switch strings.TrimSuffix(decl.typ, "?") {
+		case "String", "File":
+		default:
+			continue
+		}
+		p := decl.value.literal()
+		if strings.HasPrefix(p, "/") || windowsAbsPath.MatchString(p) {
+			diags = append(diags, newDiagnostic(
+				AbsolutePathDefault,
+				decl,
+				fmt.Sprintf(
+					"%q defaults to non-portable absolute path %q",
+					decl.name.initialName, p,
+				),
+			))
+		}
+	}
+	return diags
+}
+
+// lintDynamicContainer flags container or docker runtime attributes computed
+// from declarations, which some engines can't handle as they pull images
+// before evaluating a task.
+func lintDynamicContainer(w *WDL) []Diagnostic {
+	var diags []Diagnostic
+	for _, t := range w.Tasks {
+		for _, kv := range t.Runtime {
+			switch kv.name.initialName {
+			case "container", "docker":
+			default:
+				continue
+			}
+			if kv.value.hasReference() {
+				diags = append(diags, newDiagnostic(
+					DynamicContainer,
+					kv,
+					fmt.Sprintf(
+						"runtime %q of task %q is not a constant",
+						kv.name.initialName, t.name.initialName,
+					),
+				))
+			}
+		}
+	}
+	return diags
+}
+
+// lintInconsistentRuntimeKey flags docker runtime attributes in a document
+// which also uses container, the WDL 1.1 name of the same attribute.
+func lintInconsistentRuntimeKey(w *WDL) []Diagnostic {
+	var dockers []*valueSpec
+	hasContainer := false
+	for _, t := range w.Tasks {
+		for _, kv := range t.Runtime {
+			switch kv.name.initialName {
+			case "docker":
+				dockers = append(dockers, kv)
+			case "container":
+				hasContainer = true
+			}
+		}
+	}
+	if !hasContainer {
+		return nil
+	}
+	var diags []Diagnostic
+	for _, kv := range dockers {
+		diags = append(diags, newDiagnostic(
+			InconsistentRuntimeKey,
+			kv,
+			`runtime uses "docker" while other tasks use "container"`,
+		))
+	}
+	return diags
+}
+
+var typeKeywords = map[string]bool{
+	"Boolean":   true,
+	"Int":       true,
+	"Float":     true,
+	"String":    true,
+	"File":      true,
+	"Directory": true,
+	"Object":    true,
+	"Array":     true,
+	"Map":       true,
+	"Pair":      true,
+}
+
+// typeNames splits a raw WDL type, like Map[String,Array[Int]+]?, into the
+// names it's composed of.
+func typeNames(rawType string) []string {
+	return strings.FieldsFunc(rawType, func(r rune) bool {
+		return strings.ContainsRune("[],+? \t\r\n", r)
+	})
+}
+
+// lintUnknownType flags struct members whose type refers to neither a WDL
+// type nor a struct defined or imported by the document. Imports should be
+// resolved beforehand so that imported structs are known.
+func lintUnknownType(w *WDL) []Diagnostic {
+	var diags []Diagnostic
+	structs := w.structs()
+	for _, s := range w.Structs {
+		for _, member := range s.Members {
+			for _, name := range typeNames(member.typ) {
+				if _, ok := structs[name]; ok || typeKeywords[name] {
+					continue
+				}
+				diags = append(diags, newDiagnostic(
+					UnknownType,
+					member,
+					fmt.Sprintf(
+						"member %q of struct %q has unknown type %q",
+						member.name.initialName, s.name.initialName, name,
+					),
+				))
+			}
+		}
+	}
+	return diags
+}
+
+// calleeInputs returns inputs of the task or workflow a call targets. It
+// reports false if the call can't be resolved.
+func (w *WDL) calleeInputs(c *Call) ([]*valueSpec, bool) {
+	callee, err := w.ResolveCall(c)
+	if err != nil {
+		return nil, false
+	}
+	switch callee := callee.(type) {
+	case *Task:
+		return callee.Inputs, true
+	case *Workflow:
+		return callee.Inputs, true
+	}
+	return nil, false
+}
+
+// lintUnknownCallInput flags call inputs which are not declared as inputs by
+// the called task or workflow. Imports should be resolved beforehand so that
+// calls to imported tasks and workflows are checked.
+func lintUnknownCallInput(w *WDL) []Diagnostic {
+	if w.Workflow == nil {
+		return nil
+	}
+	var diags []Diagnostic
+	for _, c := range w.Workflow.Calls {
+		inputs, ok := w.calleeInputs(c)
+		if !ok {
+			continue
+		}
+		declared := map[string]bool{}
+		for _, input := range inputs {
+			declared[input.name.initialName] = true
+		}
+		for _, input := range c.Inputs {
+			if !declared[input.name.initialName] {
+				diags = append(diags, newDiagnostic(
+					UnknownCallInput,
+					input,
+					fmt.Sprintf(
+						"%q is not an input of %q",
+						input.name.initialName, c.callName(),
+					),
+				))
+			}
+		}
+	}
+	return diags
+}
+
+// lintMissingCallInput flags calls not supplying every required input, which
+// is neither optional nor has a default, of the called task or workflow.
+// Imports should be resolved beforehand so that calls to imported tasks and
+// workflows are checked.
+func lintMissingCallInput(w *WDL) []Diagnostic {
+	if w.Workflow == nil {
+		return nil
+	}
+	var diags []Diagnostic
+	for _, c := range w.Workflow.Calls {
+		inputs, ok := w.calleeInputs(c)
+		if !ok {
+			continue
+		}
+		supplied := map[string]bool{}
+		for _, input := range c.Inputs {
+			supplied[input.name.initialName] = true
+		}
+		for _, input := range inputs {
+			if strings.HasSuffix(input.typ, "?") || len(*input.value) > 0 ||
+				supplied[input.name.initialName] {
+				continue
+			}
+			diags = append(diags, newDiagnostic(
+				MissingCallInput,
+				c,
+				fmt.Sprintf(
+					"call %q is missing required input %q",
+					c.callName(), input.name.initialName,
+				),
+			))
+		}
+	}
+	return diags
+}
+
+// lintUnknownImportAlias flags import aliases renaming a struct which the
+// imported document neither defines nor imports, like one renamed or removed
+// by a library update. Imports should be resolved beforehand; aliases of
+// unresolved imports are not checked.
+func lintUnknownImportAlias(w *WDL) []Diagnostic {
+	var diags []Diagnostic
+	for _, is := range w.Imports {
+		if is.wdl == nil {
+			continue
+		}
+		structs := is.wdl.structs()
+		originals := make([]string, 0, len(is.importAliases))
+		for original := range is.importAliases {
+			originals = append(originals, original)
+		}
+		sort.Strings(originals)
+		for _, original := range originals {
+			if _, ok := structs[original]; ok {
+				continue
+			}
+			diags = append(diags, newDiagnostic(
+				UnknownImportAlias,
+				is,
+				fmt.Sprintf(
+					"alias %q refers to no struct in %q",
+					original, is.uri.literal(),
+				),
+			))
+		}
+	}
+	return diags
+}
+
+// lintUnknownNamespace flags calls to a qualified name with a namespace which
+// is neither the alias nor the file name of any import. Namespaces within an
+// imported document are only checked if the import is resolved.
+func lintUnknownNamespace(w *WDL) []Diagnostic {
+	if w.Workflow == nil {
+		return nil
+	}
+	var diags []Diagnostic
+	for _, c := range w.Workflow.Calls {
+		target, err := c.qualifiedName()
+		if err != nil {
+			continue
+		}
+		doc := w
+		for _, ns := range target.namespaces {
+			is := doc.importNamed(ns)
+			if is == nil {
+				diags = append(diags, newDiagnostic(
+					UnknownNamespace,
+					c,
+					fmt.Sprintf(
+						"namespace %q of call to %q refers to no import",
+						ns, target,
+					),
+				))
+				break
+			}
+			if doc = is.wdl; doc == nil {
+				break
+			}
+		}
+	}
+	return diags
+}
+
+// lintNamespaceShadowsTask flags imports whose namespace is also the name of
+// a task in the document, which makes references to either ambiguous.
+func lintNamespaceShadowsTask(w *WDL) []Diagnostic {
+	tasks := map[string]bool{}
+	for _, t := range w.Tasks {
+		tasks[t.name.initialName] = true
+	}
+	var diags []Diagnostic
+	for _, is := range w.Imports {
+		if ns := is.namespace(); tasks[ns] {
+			diags = append(diags, newDiagnostic(
+				NamespaceShadowsTask,
+				is,
+				fmt.Sprintf("import namespace %q is also a task name", ns),
+			))
+		}
+	}
+	return diags
+}
+
+// lintCallNameCollision flags calls named, by alias or callee, the same as an
+// input or declaration of the workflow, which makes a reference to the name
+// ambiguous.
+func lintCallNameCollision(w *WDL) []Diagnostic {
+	if w.Workflow == nil {
+		return nil
+	}
+	decls := map[string]bool{}
+	for _, decl := range w.Workflow.Inputs {
+		decls[decl.name.initialName] = true
+	}
+	for _, decl := range w.Workflow.PrvtDecls {
+		decls[decl.name.initialName] = true
+	}
+	var diags []Diagnostic
+	for _, c := range w.Workflow.Calls {
+		if name := c.callName(); decls[name] {
+			diags = append(diags, newDiagnostic(
+				CallNameCollision,
+				c,
+				fmt.Sprintf(
+					"call %q has the same name as a declaration", name,
+				),
+			))
+		}
+	}
+	return diags
+}
+
+// lintRequireStrictMode flags tasks whose command doesn't turn on bash strict
+// mode with set -euo pipefail, so that failures in the command aren't missed.
+func lintRequireStrictMode(w *WDL) []Diagnostic {
+	var diags []Diagnostic
+	for _, t := range w.Tasks {
+		if t.CommandStyle == 0 || t.CommandUsesStrictMode() {
+			continue
+		}
+		diags = append(diags, newDiagnostic(
+			RequireStrictMode,
+			t,
+			fmt.Sprintf(
+				"command of task %q doesn't set -euo pipefail",
+				t.name.initialName,
+			),
+		))
+	}
+	return diags
+}
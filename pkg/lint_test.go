@@ -0,0 +1,371 @@
+package wdlparser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLintAbsolutePathDefault(t *testing.T) {
+	testCases := []struct {
+		wdl  string
+		want []Diagnostic
+	}{
+		{
+			"testdata/workflow_output.wdl",
+			[]Diagnostic{
+				{
+					AbsolutePathDefault,
+					52,
+					87,
+					`"output_file" defaults to non-portable absolute path` +
+						` "/Path/to/output"`,
+					"WDL101",
+					SeverityWarning,
+				},
+			},
+		},
+		{
+			`version 1.1 workflow Test {input{File t="relative/path"}}`,
+			nil,
+		},
+	}
+	for _, tc := range testCases {
+		result, err := Antlr4Parse(tc.wdl)
+		if err != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(err), tc.wdl,
+			)
+		}
+		diags := Lint(result, AbsolutePathDefault)
+		if diff := cmp.Diff(tc.want, diags); diff != "" {
+			t.Errorf("unexpected diagnostics:\n%s", diff)
+		}
+	}
+}
+
+func TestLintUnknownType(t *testing.T) {
+	inputPath := "testdata/struct.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	expectedDiags := []Diagnostic{
+		{
+			UnknownType,
+			33,
+			42,
+			`member "name" of struct "Sample" has unknown type "Strng"`,
+			"WDL111",
+			SeverityError,
+		},
+	}
+	diags := Lint(result, UnknownType)
+	if diff := cmp.Diff(expectedDiags, diags); diff != "" {
+		t.Errorf("unexpected diagnostics:\n%s", diff)
+	}
+}
+
+func TestLintUnknownImportAlias(t *testing.T) {
+	inputPath := "testdata/import_alias.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+	if err := result.ResolveImports(); err != nil {
+		t.Fatalf("failed to resolve imports of %q: %v", inputPath, err)
+	}
+
+	expectedDiags := []Diagnostic{
+		{
+			UnknownImportAlias,
+			13,
+			82,
+			`alias "Missing" refers to no struct in "lib/chain3.wdl"`,
+			"WDL109",
+			SeverityError,
+		},
+	}
+	diags := Lint(result, UnknownImportAlias)
+	if diff := cmp.Diff(expectedDiags, diags); diff != "" {
+		t.Errorf("unexpected diagnostics:\n%s", diff)
+	}
+}
+
+func TestLintNamespaceShadowsTask(t *testing.T) {
+	inputPath := "testdata/import_shadow.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	expectedDiags := []Diagnostic{
+		{
+			NamespaceShadowsTask,
+			13,
+			43,
+			`import namespace "align" is also a task name`,
+			"WDL106",
+			SeverityWarning,
+		},
+	}
+	diags := Lint(result, NamespaceShadowsTask)
+	if diff := cmp.Diff(expectedDiags, diags); diff != "" {
+		t.Errorf("unexpected diagnostics:\n%s", diff)
+	}
+}
+
+func TestLintCallNameCollision(t *testing.T) {
+	inputPath := "testdata/workflow_call_alias_collision.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	expectedDiags := []Diagnostic{
+		{
+			CallNameCollision,
+			79,
+			144,
+			`call "sample" has the same name as a declaration`,
+			"WDL102",
+			SeverityError,
+		},
+	}
+	diags := Lint(result, CallNameCollision)
+	if diff := cmp.Diff(expectedDiags, diags); diff != "" {
+		t.Errorf("unexpected diagnostics:\n%s", diff)
+	}
+}
+
+func TestLintUnknownNamespace(t *testing.T) {
+	inputPath := "testdata/workflow_unknown_namespace.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	expectedDiags := []Diagnostic{
+		{
+			UnknownNamespace,
+			88,
+			100,
+			`namespace "qc" of call to "qc.Inner" refers to no import`,
+			"WDL110",
+			SeverityError,
+		},
+	}
+	diags := Lint(result, UnknownNamespace)
+	if diff := cmp.Diff(expectedDiags, diags); diff != "" {
+		t.Errorf("unexpected diagnostics before resolving imports:\n%s", diff)
+	}
+
+	if err := result.ResolveImports(); err != nil {
+		t.Fatalf("failed to resolve imports of %q: %v", inputPath, err)
+	}
+	expectedDiags = append(expectedDiags, Diagnostic{
+		UnknownNamespace,
+		106,
+		121,
+		`namespace "qc" of call to "ns.qc.Inner" refers to no import`,
+		"WDL110",
+		SeverityError,
+	})
+	diags = Lint(result, UnknownNamespace)
+	if diff := cmp.Diff(expectedDiags, diags); diff != "" {
+		t.Errorf("unexpected diagnostics:\n%s", diff)
+	}
+}
+
+func TestLintRequireStrictMode(t *testing.T) {
+	testCases := []struct {
+		wdl  string
+		want []Diagnostic
+	}{
+		{
+			"testdata/task_command.wdl",
+			[]Diagnostic{
+				{
+					RequireStrictMode,
+					13,
+					79,
+					`command of task "Command" doesn't set -euo pipefail`,
+					"WDL107",
+					SeverityInfo,
+				},
+			},
+		},
+		{
+			`version 1.1 task T {command <<< set -euo pipefail >>>}`,
+			nil,
+		},
+	}
+	for _, tc := range testCases {
+		result, err := Antlr4Parse(tc.wdl)
+		if err != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(err), tc.wdl,
+			)
+		}
+		diags := Lint(result, RequireStrictMode)
+		if diff := cmp.Diff(tc.want, diags); diff != "" {
+			t.Errorf("unexpected diagnostics:\n%s", diff)
+		}
+		if n := Summarize(Lint(result))[RequireStrictMode]; n != 0 {
+			t.Errorf("opt-in rule should be off by default, found %d", n)
+		}
+	}
+}
+
+func TestLintInconsistentRuntimeKey(t *testing.T) {
+	inputPath := "testdata/runtime_container.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	expectedDiags := []Diagnostic{
+		{
+			InconsistentRuntimeKey,
+			243,
+			281,
+			`runtime uses "docker" while other tasks use "container"`,
+			"WDL104",
+			SeverityWarning,
+		},
+	}
+	diags := Lint(result, InconsistentRuntimeKey)
+	if diff := cmp.Diff(expectedDiags, diags); diff != "" {
+		t.Errorf("unexpected diagnostics:\n%s", diff)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	diags := []Diagnostic{
+		{Rule: AbsolutePathDefault, Start: 0, End: 1},
+		{Rule: InconsistentRuntimeKey, Start: 2, End: 3},
+		{Rule: AbsolutePathDefault, Start: 4, End: 5},
+	}
+	expected := map[string]int{
+		AbsolutePathDefault:    2,
+		InconsistentRuntimeKey: 1,
+	}
+	if diff := cmp.Diff(expected, Summarize(diags)); diff != "" {
+		t.Errorf("unexpected summary:\n%s", diff)
+	}
+}
+
+func TestLintDynamicContainer(t *testing.T) {
+	result, err := Antlr4Parse(`version 1.1
+task Dynamic {
+    input {
+        String image_name
+    }
+    runtime {
+        container: image_name
+    }
+}
+task Constant {
+    runtime {
+        container: "ubuntu:22.04"
+    }
+}`)
+	if err != nil {
+		t.Errorf("Found %d errors, expect no errors", len(err))
+	}
+	expected := []Diagnostic{
+		{
+			DynamicContainer,
+			93,
+			113,
+			`runtime "container" of task "Dynamic" is not a constant`,
+			"WDL103",
+			SeverityWarning,
+		},
+	}
+	diags := Lint(result, DynamicContainer)
+	if diff := cmp.Diff(expected, diags); diff != "" {
+		t.Errorf("unexpected diagnostics:\n%s", diff)
+	}
+}
+
+func TestLintUnknownCallInput(t *testing.T) {
+	result, err := Antlr4Parse(`version 1.1
+workflow Test {
+    call Greet { input: name = "world", nmae = "typo" }
+    call Greet as hello { input: name = "world", nmae = "typo" }
+}
+task Greet {
+    input {
+        String name
+    }
+    command <<<
+        echo "Hello ~{name}"
+    >>>
+}`)
+	if err != nil {
+		t.Errorf("Found %d errors, expect no errors", len(err))
+	}
+	expected := []Diagnostic{
+		{
+			UnknownCallInput, 68, 80, `"nmae" is not an input of "Greet"`,
+			"WDL108", SeverityError,
+		},
+		{
+			UnknownCallInput, 133, 145, `"nmae" is not an input of "hello"`,
+			"WDL108", SeverityError,
+		},
+	}
+	diags := Lint(result, UnknownCallInput)
+	if diff := cmp.Diff(expected, diags); diff != "" {
+		t.Errorf("unexpected diagnostics:\n%s", diff)
+	}
+}
+
+func TestLintMissingCallInput(t *testing.T) {
+	result, err := Antlr4Parse(`version 1.1
+workflow Test {
+    call Greet { input: name = "world" }
+    call Greet as greet2
+}
+task Greet {
+    input {
+        String name
+        String? title
+        String greeting = "Hello"
+    }
+    command <<<
+        echo "~{greeting} ~{title} ~{name}"
+    >>>
+}`)
+	if err != nil {
+		t.Errorf("Found %d errors, expect no errors", len(err))
+	}
+	expected := []Diagnostic{
+		{
+			MissingCallInput,
+			73,
+			92,
+			`call "greet2" is missing required input "name"`,
+			"WDL105",
+			SeverityError,
+		},
+	}
+	diags := Lint(result, MissingCallInput)
+	if diff := cmp.Diff(expected, diags); diff != "" {
+		t.Errorf("unexpected diagnostics:\n%s", diff)
+	}
+}
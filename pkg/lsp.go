@@ -0,0 +1,92 @@
+package wdlparser
+
+import "strings"
+
+// An LSPPosition is a position in a document as the Language Server Protocol
+// defines it: a 0-based line and a 0-based character offset in the line,
+// counted in UTF-16 code units.
+type LSPPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// An LSPRange is a range in a document from its start to right before its end,
+// as the Language Server Protocol defines it.
+type LSPRange struct {
+	Start LSPPosition `json:"start"`
+	End   LSPPosition `json:"end"`
+}
+
+// Severities of diagnostics as the Language Server Protocol defines them.
+const (
+	LSPSeverityError       = 1
+	LSPSeverityWarning     = 2
+	LSPSeverityInformation = 3
+	LSPSeverityHint        = 4
+)
+
+// An LSPDiagnostic is a diagnostic as the Language Server Protocol defines it,
+// which can be published to a language client as is.
+type LSPDiagnostic struct {
+	Range    LSPRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+var lspSeverities = map[Severity]int{
+	SeverityError:   LSPSeverityError,
+	SeverityWarning: LSPSeverityWarning,
+	SeverityInfo:    LSPSeverityInformation,
+}
+
+// LSPDiagnostics converts syntax errors of a WDL document into Language Server
+// Protocol diagnostics, ranging over the offending tokens. The source of the
+// document is needed since lines and columns of syntax errors are 1-based and
+// in characters, while LSP positions are 0-based and in UTF-16 code units.
+// Errors without a position, like the document failing to be read, are put at
+// the start of the document.
+func LSPDiagnostics(src string, errs []SyntaxError) []LSPDiagnostic {
+	lines := strings.Split(src, "\n")
+	var diags []LSPDiagnostic
+	for _, err := range errs {
+		severity, ok := lspSeverities[err.Severity]
+		if !ok {
+			severity = LSPSeverityError
+		}
+		diags = append(diags, LSPDiagnostic{
+			Range: LSPRange{
+				lspPosition(lines, err.Line, err.Column),
+				lspPosition(lines, err.EndLine, err.EndColumn),
+			},
+			Severity: severity,
+			Code:     err.Code,
+			Source:   "wdlparser",
+			Message:  err.Msg,
+		})
+	}
+	return diags
+}
+
+// lspPosition converts a 1-based line and a column in characters into an
+// LSPPosition in the given source lines.
+func lspPosition(lines []string, line, column int) LSPPosition {
+	if line < 1 {
+		return LSPPosition{}
+	}
+	character := column
+	if line <= len(lines) {
+		character = 0
+		for i, r := range []rune(lines[line-1]) {
+			if i == column {
+				break
+			}
+			character++
+			if r > 0xFFFF { // outside of the BMP, a UTF-16 surrogate pair
+				character++
+			}
+		}
+	}
+	return LSPPosition{line - 1, character}
+}
@@ -0,0 +1,41 @@
+package wdlparser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLSPDiagnostics(t *testing.T) {
+	// 😀 is one character but two UTF-16 code units.
+	input := "version 1.1\nworkflow W {\n    String s = \"😀\" ~ 1\n}\n"
+	_, errs := Antlr4Parse(input)
+	if len(errs) != 1 {
+		t.Fatalf("Found %d errors, expect 1", len(errs))
+	}
+	errs = append(
+		errs,
+		SyntaxError{Msg: "unreadable", Code: CodeUnreadable},
+		SyntaxError{
+			Line: 2, Column: 9, EndLine: 2, EndColumn: 10,
+			Msg: "a warning", Severity: SeverityWarning,
+		},
+	)
+	expected := []LSPDiagnostic{
+		{
+			LSPRange{LSPPosition{2, 20}, LSPPosition{2, 21}},
+			LSPSeverityError, CodeSyntax, "wdlparser", errs[0].Msg,
+		},
+		{
+			LSPRange{LSPPosition{0, 0}, LSPPosition{0, 0}},
+			LSPSeverityError, CodeUnreadable, "wdlparser", "unreadable",
+		},
+		{
+			LSPRange{LSPPosition{1, 9}, LSPPosition{1, 10}},
+			LSPSeverityWarning, "", "wdlparser", "a warning",
+		},
+	}
+	if diff := cmp.Diff(expected, LSPDiagnostics(input, errs)); diff != "" {
+		t.Errorf("unexpected LSP diagnostics:\n%s", diff)
+	}
+}
@@ -0,0 +1,74 @@
+package wdlparser
+
+import "fmt"
+
+// DuplicateName is the rule name of diagnostics on tasks, structs or workflows
+// defined more than once when merging documents.
+const DuplicateName = "DuplicateName"
+
+// Merge combines tasks, structs and the workflow of documents into one
+// document, which takes the version of the first document. A task or struct
+// whose name is taken by an earlier document, or a workflow after the first
+// one, is left out and reported. Nodes are shared with, and still belong to,
+// the given documents; imports are not merged.
+func Merge(docs ...*WDL) (*WDL, []Diagnostic) {
+	merged := NewWDL("", 0)
+	var diags []Diagnostic
+	tasks := map[string]*WDL{}
+	structs := map[string]*WDL{}
+	var workflowDoc *WDL
+	for _, doc := range docs {
+		if merged.Version == "" {
+			merged.Version = doc.Version
+		}
+		for _, s := range doc.Structs {
+			if first, ok := structs[s.name.initialName]; ok {
+				diags = append(diags, newDiagnostic(
+					DuplicateName,
+					s,
+					fmt.Sprintf(
+						"struct %q in %q is already defined in %q",
+						s.name.initialName, doc.Path, first.Path,
+					),
+				))
+				continue
+			}
+			structs[s.name.initialName] = doc
+			merged.Structs = append(merged.Structs, s)
+		}
+		if doc.Workflow != nil {
+			if workflowDoc != nil {
+				diags = append(diags, newDiagnostic(
+					DuplicateName,
+					doc.Workflow,
+					fmt.Sprintf(
+						"workflow %q in %q is defined after workflow %q in %q",
+						doc.Workflow.name.initialName,
+						doc.Path,
+						merged.Workflow.name.initialName,
+						workflowDoc.Path,
+					),
+				))
+			} else {
+				workflowDoc = doc
+				merged.Workflow = doc.Workflow
+			}
+		}
+		for _, t := range doc.Tasks {
+			if first, ok := tasks[t.name.initialName]; ok {
+				diags = append(diags, newDiagnostic(
+					DuplicateName,
+					t,
+					fmt.Sprintf(
+						"task %q in %q is already defined in %q",
+						t.name.initialName, doc.Path, first.Path,
+					),
+				))
+				continue
+			}
+			tasks[t.name.initialName] = doc
+			merged.Tasks = append(merged.Tasks, t)
+		}
+	}
+	return merged, diags
+}
@@ -0,0 +1,58 @@
+package wdlparser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMerge(t *testing.T) {
+	var docs []*WDL
+	for _, inputPath := range []string{
+		"testdata/runtime_container.wdl",
+		"testdata/workflow_scatter.wdl",
+		"testdata/version1_1.wdl",
+	} {
+		result, err := Antlr4Parse(inputPath)
+		if err != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(err), inputPath,
+			)
+		}
+		docs = append(docs, result)
+	}
+	docs[1].Tasks[0].name.initialName = "Align"
+
+	merged, diags := Merge(docs...)
+	var tasks []string
+	for _, task := range merged.Tasks {
+		tasks = append(tasks, task.name.initialName)
+	}
+	expectedTasks := []string{"Align", "Sort", "Index", "Merge", "WriteGreeting"}
+	if diff := cmp.Diff(expectedTasks, tasks); diff != "" {
+		t.Errorf("unexpected merged tasks:\n%s", diff)
+	}
+	if merged.Workflow != docs[1].Workflow {
+		t.Errorf("merged workflow should be the first workflow")
+	}
+
+	var rules, msgs []string
+	for _, d := range diags {
+		rules = append(rules, d.Rule)
+		msgs = append(msgs, d.Msg)
+	}
+	expectedMsgs := []string{
+		`task "Align" in "testdata/workflow_scatter.wdl" is already defined` +
+			` in "testdata/runtime_container.wdl"`,
+		`workflow "HelloWorld" in "testdata/version1_1.wdl" is defined after` +
+			` workflow "Scatter" in "testdata/workflow_scatter.wdl"`,
+	}
+	if diff := cmp.Diff(expectedMsgs, msgs); diff != "" {
+		t.Errorf("unexpected diagnostics:\n%s", diff)
+	}
+	if diff := cmp.Diff(
+		[]string{DuplicateName, DuplicateName}, rules,
+	); diff != "" {
+		t.Errorf("unexpected diagnostic rules:\n%s", diff)
+	}
+}
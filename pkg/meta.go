@@ -0,0 +1,133 @@
+package wdlparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	parser "github.com/yunhailuo/wdlparser/pkg/antlr4_grammar/1_1"
+)
+
+// metaValue converts a metadata value into a go value which is one of nil,
+// bool, int64, float64, string, []interface{} and map[string]interface{}.
+func metaValue(ctx *parser.Meta_valueContext) interface{} {
+	switch {
+	case ctx.MetaNull() != nil:
+		return nil
+	case ctx.MetaBool() != nil:
+		b, _ := strconv.ParseBool(ctx.MetaBool().GetText())
+		return b
+	case ctx.MetaInt() != nil:
+		i, _ := strconv.ParseInt(ctx.MetaInt().GetText(), 10, 64)
+		return i
+	case ctx.MetaFloat() != nil:
+		f, _ := strconv.ParseFloat(ctx.MetaFloat().GetText(), 64)
+		return f
+	case ctx.Meta_string() != nil:
+		var s strings.Builder
+		part := ctx.Meta_string().(*parser.Meta_stringContext).
+			Meta_string_part().(*parser.Meta_string_partContext)
+		for _, p := range part.AllMetaStringPart() {
+			s.WriteString(unescape(p.GetText()))
+		}
+		return s.String()
+	case ctx.Meta_array() != nil:
+		array := ctx.Meta_array().(*parser.Meta_arrayContext)
+		values := []interface{}{}
+		for _, v := range array.AllMeta_value() {
+			values = append(values, metaValue(v.(*parser.Meta_valueContext)))
+		}
+		return values
+	case ctx.Meta_object() != nil:
+		object := ctx.Meta_object().(*parser.Meta_objectContext)
+		kvs := map[string]interface{}{}
+		for _, kv := range object.AllMeta_object_kv() {
+			kv := kv.(*parser.Meta_object_kvContext)
+			kvs[kv.MetaObjectIdentifier().GetText()] = metaValue(
+				kv.Meta_value().(*parser.Meta_valueContext),
+			)
+		}
+		return kvs
+	}
+	return nil
+}
+
+// Constraints are restrictions on the value of an input described in
+// parameter_meta.
+type Constraints struct {
+	Pattern string   // pattern of valid values, like "*.bam"
+	Choices []string // valid values to choose from
+}
+
+// InputConstraints returns the pattern and choices given to an input in the
+// parameter_meta section of a task. It reports false if the input has no
+// parameter_meta entry with either of them.
+func (t *Task) InputConstraints(name string) (Constraints, bool) {
+	var c Constraints
+	for _, pm := range t.ParameterMeta {
+		if pm.name.initialName != name {
+			continue
+		}
+		object, ok := pm.meta.(map[string]interface{})
+		if !ok {
+			return c, false
+		}
+		pattern, hasPattern := object["pattern"].(string)
+		choices, hasChoices := object["choices"].([]interface{})
+		if !hasPattern && !hasChoices {
+			return c, false
+		}
+		c.Pattern = pattern
+		for _, choice := range choices {
+			if s, ok := choice.(string); ok {
+				c.Choices = append(c.Choices, s)
+			}
+		}
+		return c, true
+	}
+	return c, false
+}
+
+// UnknownParameterMeta is the rule name of diagnostics on parameter_meta keys
+// which describe no input or output.
+const UnknownParameterMeta = "UnknownParameterMeta"
+
+// checkParameterMeta reports parameter_meta keys missing from given inputs and
+// outputs.
+func checkParameterMeta(
+	parameterMeta []*valueSpec, params ...[]*valueSpec,
+) []error {
+	declared := map[string]bool{}
+	for _, decls := range params {
+		for _, decl := range decls {
+			declared[decl.name.initialName] = true
+		}
+	}
+	var errs []error
+	for _, pm := range parameterMeta {
+		if declared[pm.name.initialName] {
+			continue
+		}
+		errs = append(errs, newDiagnostic(
+			UnknownParameterMeta,
+			pm,
+			fmt.Sprintf(
+				"parameter_meta %q describes no input or output",
+				pm.name.initialName,
+			),
+		))
+	}
+	return errs
+}
+
+// CheckParameterMeta finds parameter_meta keys of a task which describe none
+// of its inputs and outputs, like ones left behind by renaming an input.
+func (t *Task) CheckParameterMeta() []error {
+	return checkParameterMeta(t.ParameterMeta, t.Inputs, t.Outputs)
+}
+
+// CheckParameterMeta finds parameter_meta keys of a workflow which describe
+// none of its inputs and outputs.
+func (w *Workflow) CheckParameterMeta() []error {
+	return checkParameterMeta(w.ParameterMeta, w.Inputs, w.Outputs)
+}
@@ -0,0 +1,146 @@
+package wdlparser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTaskInputConstraints(t *testing.T) {
+	inputPath := "testdata/task_input_constraints.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	testCases := []struct {
+		input string
+		want  Constraints
+		ok    bool
+	}{
+		{"bam", Constraints{Pattern: "*.bam"}, true},
+		{"mode", Constraints{Choices: []string{"fast", "accurate"}}, true},
+		{"threads", Constraints{}, false},
+		{"missing", Constraints{}, false},
+	}
+	for _, tc := range testCases {
+		c, ok := result.Tasks[0].InputConstraints(tc.input)
+		if ok != tc.ok {
+			t.Errorf("input %q should have constraints %t", tc.input, tc.ok)
+		}
+		if diff := cmp.Diff(tc.want, c); diff != "" {
+			t.Errorf("unexpected constraints of %q:\n%s", tc.input, diff)
+		}
+	}
+}
+
+func TestMisplacedMeta(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected SyntaxError
+	}{
+		{
+			`version 1.1
+struct S {
+    String a
+    parameter_meta {
+        a: "Sample name"
+    }
+}`,
+			SyntaxError{
+				Line:      4,
+				Column:    4,
+				EndLine:   4,
+				EndColumn: 18,
+				Token:     "parameter_meta",
+				Stop:      53,
+				Msg: `"parameter_meta" section is dropped since WDL 1.1 ` +
+					`has no struct metadata, which is new in WDL development`,
+				Code:     CodeMisplacedMeta,
+				Severity: SeverityError,
+			},
+		},
+		{
+			`version 1.1
+meta {
+    author: "me"
+}`,
+			SyntaxError{
+				Line:      2,
+				Column:    0,
+				EndLine:   2,
+				EndColumn: 4,
+				Token:     "meta",
+				Stop:      15,
+				Msg: `"meta" section is dropped since WDL 1.1 only has ` +
+					`metadata in workflows and tasks`,
+				Code:     CodeMisplacedMeta,
+				Severity: SeverityError,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		_, errs := Antlr4Parse(tc.input)
+		if diff := cmp.Diff([]SyntaxError{tc.expected}, errs); diff != "" {
+			t.Errorf("unexpected syntax errors:\n%s", diff)
+		}
+	}
+}
+
+func TestCheckParameterMeta(t *testing.T) {
+	input := `version 1.1
+workflow W {
+    input {
+        String sample
+    }
+    parameter_meta {
+        sample: "Sample name"
+        bam: "Renamed to sample"
+    }
+}
+task T {
+    input {
+        File reads
+    }
+    command <<< >>>
+    output {
+        File out = "out.txt"
+    }
+    parameter_meta {
+        reads: "Input reads"
+        out: "Output file"
+        threads: "Removed input"
+    }
+}`
+	result, err := Antlr4Parse(input)
+	if err != nil {
+		t.Errorf("Found %d errors, expect no errors", len(err))
+	}
+
+	expected := []error{
+		Diagnostic{
+			UnknownParameterMeta, 124, 147,
+			`parameter_meta "bam" describes no input or output`,
+			"WDL113", SeverityWarning,
+		},
+	}
+	if diff := cmp.Diff(
+		expected, result.Workflow.CheckParameterMeta(),
+	); diff != "" {
+		t.Errorf("unexpected workflow diagnostics:\n%s", diff)
+	}
+	expected = []error{
+		Diagnostic{
+			UnknownParameterMeta, 356, 379,
+			`parameter_meta "threads" describes no input or output`,
+			"WDL113", SeverityWarning,
+		},
+	}
+	if diff := cmp.Diff(
+		expected, result.Tasks[0].CheckParameterMeta(),
+	); diff != "" {
+		t.Errorf("unexpected task diagnostics:\n%s", diff)
+	}
+}
@@ -5,8 +5,11 @@ source files. Language specifications can be found at https://github.com/openwdl
 package wdlparser
 
 import (
+	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/antlr/antlr4/runtime/Go/antlr"
@@ -21,6 +24,7 @@ const (
 	_   wdlSection = iota // leave 0 as nodeKind zero value; start from 1
 	doc                   // WDL document
 	imp                   // import
+	stc                   // struct
 	wfl                   // workflow
 	cal                   // call
 	tsk                   // task
@@ -43,7 +47,7 @@ func (nks *sectionStack) pop() {
 		*nks = (*nks)[:stackDepth-1]
 		return
 	}
-	log.Fatalf("pop error: node kind stack %v is empty", *nks)
+	log.Panicf("pop error: node kind stack %v is empty", *nks)
 }
 
 func (nks *sectionStack) contains(nk wdlSection) bool {
@@ -57,14 +61,41 @@ func (nks *sectionStack) contains(nk wdlSection) bool {
 
 type wdlv1_1Listener struct {
 	*parser.BaseWdlV1_1ParserListener
-	wdl          *WDL
-	sectionStack sectionStack
-	astContext   struct {
+	wdl           *WDL
+	sectionStack  sectionStack
+	warningErrors []SyntaxError // warnings as errors for WarningsAsErrors
+	literalErrors []SyntaxError // literals their types can't hold
+	astContext    struct {
 		importNode   *importSpec
 		workflowNode *Workflow
 		callNode     *Call
 		taskNode     *Task
 		exprNode     *expression
+		blocks       []block // enclosing scatters and conditionals
+	}
+}
+
+// A block is a scatter or conditional being parsed along with the container
+// of its expression.
+type block struct {
+	n    node
+	expr *expression
+}
+
+// addToBlock adds a declaration, call or block in a workflow body to its
+// innermost enclosing scatter or conditional, if any, or to the workflow.
+func (l *wdlv1_1Listener) addToBlock(n node) {
+	blocks := l.astContext.blocks
+	if len(blocks) == 0 {
+		n.setParent(l.astContext.workflowNode)
+		return
+	}
+	n.setParent(blocks[len(blocks)-1].n)
+	switch b := blocks[len(blocks)-1].n.(type) {
+	case *Scatter:
+		b.Body = append(b.Body, n)
+	case *Conditional:
+		b.Body = append(b.Body, n)
 	}
 }
 
@@ -72,6 +103,17 @@ func newWdlv1_1Listener(wdl *WDL) *wdlv1_1Listener {
 	return &wdlv1_1Listener{wdl: wdl}
 }
 
+// sourceText returns the source text of a parsed rule, including hidden
+// tokens like whitespace and comments. It's empty for a rule which is missing
+// from the source, as recovered from a syntax error.
+func sourceText(ctx antlr.ParserRuleContext) string {
+	start, stop := ctx.GetStart(), ctx.GetStop()
+	if stop == nil || stop.GetStop() < start.GetStart() {
+		return ""
+	}
+	return start.GetInputStream().GetText(start.GetStart(), stop.GetStop())
+}
+
 // Manage section stack when listener walks
 func (l *wdlv1_1Listener) EnterEveryRule(ctx antlr.ParserRuleContext) {
 	switch ctx.(type) {
@@ -79,6 +121,8 @@ func (l *wdlv1_1Listener) EnterEveryRule(ctx antlr.ParserRuleContext) {
 		l.sectionStack.push(doc)
 	case *parser.Import_docContext:
 		l.sectionStack.push(imp)
+	case *parser.Wdl_structContext:
+		l.sectionStack.push(stc)
 	case *parser.WorkflowContext:
 		l.sectionStack.push(wfl)
 	case *parser.CallContext:
@@ -104,6 +148,7 @@ func (l *wdlv1_1Listener) ExitEveryRule(ctx antlr.ParserRuleContext) {
 	switch ctx.(type) {
 	case *parser.DocumentContext,
 		*parser.Import_docContext,
+		*parser.Wdl_structContext,
 		*parser.WorkflowContext,
 		*parser.CallContext,
 		*parser.TaskContext,
@@ -119,6 +164,9 @@ func (l *wdlv1_1Listener) ExitEveryRule(ctx antlr.ParserRuleContext) {
 
 // Parse WDL version
 func (l *wdlv1_1Listener) ExitVersion(ctx *parser.VersionContext) {
+	if ctx.ReleaseVersion() == nil {
+		return // missing, as reported by the parser
+	}
 	l.wdl.Version = ctx.ReleaseVersion().GetText()
 }
 
@@ -153,6 +201,17 @@ func (l *wdlv1_1Listener) ExitImport_alias(ctx *parser.Import_aliasContext) {
 	l.astContext.importNode.importAliases[k] = v
 }
 
+// Parse struct
+func (l *wdlv1_1Listener) EnterWdl_struct(ctx *parser.Wdl_structContext) {
+	n := NewStruct(
+		ctx.GetStart().GetStart(),
+		ctx.GetStop().GetStop(),
+		l.wdl,
+		ctx.Identifier().GetText(),
+	)
+	l.wdl.Structs = append(l.wdl.Structs, n)
+}
+
 // Parse workflow
 func (l *wdlv1_1Listener) EnterWorkflow(ctx *parser.WorkflowContext) {
 	l.wdl.Workflow = NewWorkflow(
@@ -161,9 +220,20 @@ func (l *wdlv1_1Listener) EnterWorkflow(ctx *parser.WorkflowContext) {
 		l.wdl,
 		ctx.Identifier().GetText(),
 	)
+	l.wdl.Workflow.bodyStart = ctx.LBRACE().GetSymbol().GetStart()
+	l.wdl.Workflow.bodyEnd = ctx.GetStop().GetStop()
+	if rbrace := ctx.RBRACE(); rbrace != nil { // missing in invalid WDL
+		l.wdl.Workflow.bodyEnd = rbrace.GetSymbol().GetStop()
+	}
 	l.astContext.workflowNode = l.wdl.Workflow
 }
 
+func (l *wdlv1_1Listener) EnterWorkflow_output(
+	ctx *parser.Workflow_outputContext,
+) {
+	l.wdl.Workflow.HasOutputSection = true
+}
+
 // Parse call
 func (l *wdlv1_1Listener) EnterCall(ctx *parser.CallContext) {
 	n := NewCall(
@@ -175,11 +245,20 @@ func (l *wdlv1_1Listener) EnterCall(ctx *parser.CallContext) {
 	l.astContext.workflowNode.Calls = append(
 		l.astContext.workflowNode.Calls, n,
 	)
+	l.addToBlock(n)
 	l.astContext.callNode = n
 }
 
 func (l *wdlv1_1Listener) ExitCall_name(ctx *parser.Call_nameContext) {
 	l.astContext.callNode.name.initialName = ctx.GetText()
+	for _, segment := range ctx.AllIdentifier() {
+		if segment.GetSymbol().GetTokenIndex() < 0 {
+			continue // conjured up by error recovery for a missing name
+		}
+		l.astContext.callNode.Target = append(
+			l.astContext.callNode.Target, segment.GetText(),
+		)
+	}
 }
 
 func (l *wdlv1_1Listener) ExitCall_alias(ctx *parser.Call_aliasContext) {
@@ -187,7 +266,9 @@ func (l *wdlv1_1Listener) ExitCall_alias(ctx *parser.Call_aliasContext) {
 }
 
 func (l *wdlv1_1Listener) ExitCall_after(ctx *parser.Call_afterContext) {
-	l.astContext.callNode.After = ctx.Identifier().GetText()
+	l.astContext.callNode.After = append(
+		l.astContext.callNode.After, ctx.Identifier().GetText(),
+	)
 }
 
 func (l *wdlv1_1Listener) EnterCall_input(ctx *parser.Call_inputContext) {
@@ -207,15 +288,72 @@ func (l *wdlv1_1Listener) ExitCall_input(ctx *parser.Call_inputContext) {
 		"",
 	)
 	v.name.isReference = true
+	v.setParent(l.astContext.callNode)
 	if ctx.Expr() != nil {
 		v.value = &l.astContext.exprNode.subExprs.pop().rpn
+		v.value.adopt(v)
+		v.raw = sourceText(ctx.Expr())
 		l.astContext.exprNode = nil
 	} else {
+		// input: x is a shorthand for x = x
 		v.value = &exprRPN{newIdentifier(ctx.Identifier().GetText(), true)}
 	}
 	l.astContext.callNode.Inputs = append(l.astContext.callNode.Inputs, v)
 }
 
+// Parse scatter and conditional
+func (l *wdlv1_1Listener) enterBlock(n node) {
+	l.addToBlock(n)
+	if len(l.astContext.blocks) == 0 {
+		l.astContext.workflowNode.Blocks = append(
+			l.astContext.workflowNode.Blocks, n,
+		)
+	}
+	e := newExpression(n.getStart(), n.getEnd())
+	l.astContext.blocks = append(l.astContext.blocks, block{n, e})
+	l.astContext.exprNode = e
+}
+
+// exitBlock returns the expression of the block being exited.
+func (l *wdlv1_1Listener) exitBlock() *exprRPN {
+	last := len(l.astContext.blocks) - 1
+	b := l.astContext.blocks[last]
+	l.astContext.blocks = l.astContext.blocks[:last]
+	rpn := &b.expr.subExprs.pop().rpn
+	rpn.adopt(b.n)
+	return rpn
+}
+
+func (l *wdlv1_1Listener) EnterScatter(ctx *parser.ScatterContext) {
+	l.enterBlock(NewScatter(
+		ctx.GetStart().GetStart(),
+		ctx.GetStop().GetStop(),
+		l.astContext.workflowNode,
+		ctx.Identifier().GetText(),
+	))
+}
+
+func (l *wdlv1_1Listener) ExitScatter(ctx *parser.ScatterContext) {
+	scatter := l.astContext.blocks[len(l.astContext.blocks)-1].n.(*Scatter)
+	scatter.Collection = l.exitBlock()
+	scatter.raw = sourceText(ctx.Expr())
+}
+
+func (l *wdlv1_1Listener) EnterConditional(ctx *parser.ConditionalContext) {
+	l.enterBlock(NewConditional(
+		ctx.GetStart().GetStart(),
+		ctx.GetStop().GetStop(),
+		l.astContext.workflowNode,
+	))
+}
+
+func (l *wdlv1_1Listener) ExitConditional(ctx *parser.ConditionalContext) {
+	conditional := l.astContext.blocks[len(l.astContext.blocks)-1].
+		n.(*Conditional)
+	conditional.Condition = l.exitBlock()
+	conditional.raw = sourceText(ctx.Expr())
+}
+
 // Parse a task
 // TODO: wrong parsing to be fixed
 func (l *wdlv1_1Listener) EnterTask(ctx *parser.TaskContext) {
@@ -225,16 +363,14 @@ func (l *wdlv1_1Listener) EnterTask(ctx *parser.TaskContext) {
 		l.wdl,
 		ctx.Identifier().GetText(),
 	)
+	l.astContext.taskNode.bodyStart = ctx.LBRACE().GetSymbol().GetStart()
+	l.astContext.taskNode.bodyEnd = ctx.GetStop().GetStop()
+	if rbrace := ctx.RBRACE(); rbrace != nil { // missing in invalid WDL
+		l.astContext.taskNode.bodyEnd = rbrace.GetSymbol().GetStop()
+	}
 	l.wdl.Tasks = append(l.wdl.Tasks, l.astContext.taskNode)
 }
 
-func (l *wdlv1_1Listener) EnterTask_command(ctx *parser.Task_commandContext) {
-	l.astContext.taskNode.Command = append(
-		l.astContext.taskNode.Command,
-		ctx.Task_command_string_part().GetText(),
-	)
-}
-
 func (l *wdlv1_1Listener) EnterTask_runtime_kv(
 	ctx *parser.Task_runtime_kvContext,
 ) {
@@ -254,12 +390,18 @@ func (l *wdlv1_1Listener) ExitTask_runtime_kv(
 		"",
 	)
 	v.value = &l.astContext.exprNode.subExprs.pop().rpn
+	v.value.adopt(v)
+	v.raw = sourceText(ctx.Expr())
+	v.setParent(l.astContext.taskNode)
 	l.astContext.exprNode = nil
 	l.astContext.taskNode.Runtime = append(l.astContext.taskNode.Runtime, v)
 }
 
 // Parse any declaration
 func (l *wdlv1_1Listener) EnterUnbound_decls(ctx *parser.Unbound_declsContext) {
+	if ctx.Identifier() == nil { // missing in invalid WDL
+		return
+	}
 	n := newValueSpec(
 		ctx.GetStart().GetStart(),
 		ctx.GetStop().GetStop(),
@@ -269,12 +411,16 @@ func (l *wdlv1_1Listener) EnterUnbound_decls(ctx *parser.Unbound_declsContext) {
 	// Try to figure out which section this valueSpec belongs to
 	switch {
 	case l.sectionStack.contains(wfl):
+		n.setParent(l.wdl.Workflow)
 		l.wdl.Workflow.Inputs = append(l.wdl.Workflow.Inputs, n)
 	case l.sectionStack.contains(tsk):
 		taskNode := l.wdl.Tasks[len(l.wdl.Tasks)-1]
+		n.setParent(taskNode)
 		taskNode.Inputs = append(taskNode.Inputs, n)
-	default:
-		l.wdl.Structs = append(l.wdl.Structs, n)
+	case l.sectionStack.contains(stc):
+		structNode := l.wdl.Structs[len(l.wdl.Structs)-1]
+		n.setParent(structNode)
+		structNode.Members = append(structNode.Members, n)
 	}
 }
 
@@ -286,6 +432,10 @@ func (l *wdlv1_1Listener) EnterBound_decls(ctx *parser.Bound_declsContext) {
 }
 
 func (l *wdlv1_1Listener) ExitBound_decls(ctx *parser.Bound_declsContext) {
+	if ctx.Identifier() == nil || ctx.Expr() == nil { // missing in invalid WDL
+		l.astContext.exprNode = nil
+		return
+	}
 	n := newValueSpec(
 		ctx.GetStart().GetStart(),
 		ctx.GetStop().GetStop(),
@@ -293,10 +443,13 @@ func (l *wdlv1_1Listener) ExitBound_decls(ctx *parser.Bound_declsContext) {
 		ctx.Wdl_type().GetText(),
 	)
 	n.value = &l.astContext.exprNode.subExprs.pop().rpn
+	n.value.adopt(n)
+	n.raw = sourceText(ctx.Expr())
 	l.astContext.exprNode = nil
 	// Try to figure out which section this valueSpec belongs to
 	switch {
 	case l.sectionStack.contains(wfl):
+		n.setParent(l.wdl.Workflow)
 		switch {
 		case l.sectionStack.contains(ipt):
 			l.wdl.Workflow.Inputs = append(l.wdl.Workflow.Inputs, n)
@@ -304,9 +457,11 @@ func (l *wdlv1_1Listener) ExitBound_decls(ctx *parser.Bound_declsContext) {
 			l.wdl.Workflow.Outputs = append(l.wdl.Workflow.Outputs, n)
 		default:
 			l.wdl.Workflow.PrvtDecls = append(l.wdl.Workflow.PrvtDecls, n)
+			l.addToBlock(n)
 		}
 	case l.sectionStack.contains(tsk):
 		taskNode := l.wdl.Tasks[len(l.wdl.Tasks)-1]
+		n.setParent(taskNode)
 		switch {
 		case l.sectionStack.contains(ipt):
 			taskNode.Inputs = append(taskNode.Inputs, n)
@@ -315,8 +470,6 @@ func (l *wdlv1_1Listener) ExitBound_decls(ctx *parser.Bound_declsContext) {
 		default:
 			taskNode.PrvtDecls = append(taskNode.PrvtDecls, n)
 		}
-	default:
-		l.wdl.Structs = append(l.wdl.Structs, n)
 	}
 }
 
@@ -329,8 +482,11 @@ func (l *wdlv1_1Listener) ExitMeta_kv(ctx *parser.Meta_kvContext) {
 		"",
 	)
 	v.value.append(ctx.Meta_value().GetText())
+	v.raw = sourceText(ctx.Meta_value())
+	v.meta = metaValue(ctx.Meta_value().(*parser.Meta_valueContext))
 	switch {
 	case l.sectionStack.contains(wfl):
+		v.setParent(l.wdl.Workflow)
 		switch {
 		case l.sectionStack.contains(mtd):
 			l.wdl.Workflow.Meta = append(l.wdl.Workflow.Meta, v)
@@ -341,6 +497,7 @@ func (l *wdlv1_1Listener) ExitMeta_kv(ctx *parser.Meta_kvContext) {
 		}
 	case l.sectionStack.contains(tsk):
 		taskNode := l.wdl.Tasks[len(l.wdl.Tasks)-1]
+		v.setParent(taskNode)
 		switch {
 		case l.sectionStack.contains(mtd):
 			taskNode.Meta = append(taskNode.Meta, v)
@@ -350,40 +507,237 @@ func (l *wdlv1_1Listener) ExitMeta_kv(ctx *parser.Meta_kvContext) {
 	}
 }
 
-// Antlr4Parse parse a WDL document into WDL
-func Antlr4Parse(input string) (*WDL, []wdlSyntaxError) {
-	inputInfo, err := os.Stat(input)
-	var inputStream antlr.CharStream
-	var path string = input
-	if err != nil {
+// ParseOptions controls how a WDL document is parsed.
+type ParseOptions struct {
+	FailFast bool // stop parsing at the first syntax error
+	// Ambiguities, if not nil, collects reports of ambiguities in the grammar
+	// and of prediction falling back to full context. Parsing is slower since
+	// the parser has to predict with full context to make such reports.
+	Ambiguities *[]string
+	// WarningsAsErrors reports warnings, like those on deprecated constructs,
+	// as syntax errors too. Warnings are always kept in WDL.Warnings.
+	WarningsAsErrors bool
+}
+
+// Antlr4Parse parses a WDL document into WDL. The input is parsed as a path if
+// a file exists there, or as WDL source otherwise. Use ParseFile or
+// ParseString to tell which it is explicitly.
+func Antlr4Parse(input string) (*WDL, []SyntaxError) {
+	return Antlr4ParseWithOptions(input, ParseOptions{})
+}
+
+// Antlr4ParseWithOptions is like Antlr4Parse but parses with options. Under
+// FailFast, it returns the first syntax error alone along with a document
+// without any content.
+func Antlr4ParseWithOptions(
+	input string, opts ParseOptions,
+) (*WDL, []SyntaxError) {
+	if _, err := os.Stat(input); err != nil {
 		log.Println(
 			"Input is not a valid file path" +
 				" so guessing it's a WDL document in string.",
 		)
-		path = ""
-		inputStream = antlr.NewInputStream(input)
-	} else if inputInfo.IsDir() {
-		log.Fatalf(
-			"%v is a directory; need a file path or WDL document string.",
-			path,
-		)
-	} else {
+		return ParseString(input, opts)
+	}
+	return ParseFile(input, opts)
+}
+
+// ParseFile parses the WDL document at a path. If the path can't be read,
+// like a directory, the error is returned as the only SyntaxError, without
+// a position, along with a document without any content.
+func ParseFile(path string, opts ParseOptions) (*WDL, []SyntaxError) {
+	info, err := os.Stat(path)
+	if err == nil && info.IsDir() {
+		err = fmt.Errorf("%v is a directory, not a WDL document", path)
+	}
+	var inputStream antlr.CharStream
+	if err == nil {
 		inputStream, err = antlr.NewFileStream(path)
+	}
+	if err != nil {
+		return NewWDL(path, 0), []SyntaxError{newReadError(err)}
+	}
+	return parseStream(path, inputStream, opts)
+}
+
+// ParseString parses WDL source into a document without a path.
+func ParseString(src string, opts ParseOptions) (*WDL, []SyntaxError) {
+	return parseStream("", antlr.NewInputStream(src), opts)
+}
+
+// ValidateOnly checks the syntax of a WDL document without building a parse
+// tree or a document, which is faster for large documents. Like Antlr4Parse,
+// the input is parsed as a path if a file exists there, or as WDL source
+// otherwise.
+func ValidateOnly(input string) []SyntaxError {
+	var inputStream antlr.CharStream = antlr.NewInputStream(input)
+	if info, err := os.Stat(input); err == nil && !info.IsDir() {
+		fileStream, err := antlr.NewFileStream(input)
 		if err != nil {
-			log.Fatal(err)
+			return []SyntaxError{newReadError(err)}
 		}
+		inputStream = fileStream
 	}
+	_, _, errorListener := predict(inputStream, ParseOptions{}, false)
+	return errorListener.syntaxErrors
+}
+
+// ParseDir parses every WDL document, a file named with the .wdl extension, in
+// a directory and its subdirectories. Documents and their syntax errors, if
+// any, are keyed by their paths. A document failing to parse doesn't stop
+// others from being parsed, nor does a subdirectory failing to be read, whose
+// error is keyed by its path.
+func ParseDir(dir string) (map[string]*WDL, map[string][]SyntaxError) {
+	docs := map[string]*WDL{}
+	errs := map[string][]SyntaxError{}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			errs[path] = append(errs[path], newReadError(err))
+		case !d.IsDir() && filepath.Ext(path) == ".wdl":
+			doc, docErrs := ParseFile(path, ParseOptions{})
+			docs[path] = doc
+			if docErrs != nil {
+				errs[path] = docErrs
+			}
+		}
+		return nil
+	})
+	return docs, errs
+}
 
+// parseTree parses a WDL document predicting with a given mode, into a parse
+// tree if buildTree. Unless verbose, errors are collected but not printed.
+// Under FailFast, it returns no tree after the first syntax error.
+func parseTree(
+	inputStream antlr.CharStream,
+	opts ParseOptions,
+	mode int,
+	verbose bool,
+	buildTree bool,
+) (
+	tree parser.IDocumentContext,
+	stream *antlr.CommonTokenStream,
+	errorListener *wdlErrorListener,
+) {
+	errorListener = newWdlErrorListener(true, opts.FailFast)
+	errorListener.ambiguities = opts.Ambiguities
 	lexer := parser.NewWdlV1_1Lexer(inputStream)
-	stream := antlr.NewCommonTokenStream(lexer, 0)
+	if !verbose {
+		lexer.RemoveErrorListeners()
+	}
+	lexer.AddErrorListener(errorListener)
+	stream = antlr.NewCommonTokenStream(lexer, 0)
 	p := parser.NewWdlV1_1Parser(stream)
-	p.BuildParseTrees = false
-	p.Interpreter.SetPredictionMode(antlr.PredictionModeSLL)
-	errorListener := newWdlErrorListener(true)
+	p.Interpreter.SetPredictionMode(mode)
+	if !verbose {
+		p.RemoveErrorListeners()
+	}
 	p.AddErrorListener(errorListener)
-	p.BuildParseTrees = true
-	wdl := NewWDL(path, inputStream.Size())
-	antlr.ParseTreeWalkerDefault.Walk(newWdlv1_1Listener(wdl), p.Document())
+	// The default strategy recovers from a syntax error by skipping or
+	// conjuring tokens, so that later independent errors are reported too,
+	// unlike the bail strategy. FailFast stops at the first error instead.
+	p.SetErrorHandler(antlr.NewDefaultErrorStrategy())
+	// Set only once: the listener building a document walks the parse tree,
+	// so it's only skipped when syntax is checked alone.
+	p.BuildParseTrees = buildTree
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(errFailFast); !ok {
+				panic(r)
+			}
+			tree = nil
+		}
+	}()
+	return p.Document(), stream, errorListener
+}
+
+// predict parses a WDL document in the fast SLL prediction mode first, which
+// may report errors for some valid documents, and parses it again in the LL
+// mode if there's any error. Errors are only reported if the LL mode fails
+// too. With Ambiguities, it's only parsed in the LL mode to report
+// ambiguities, which are collected without printing them nor errors.
+func predict(
+	inputStream antlr.CharStream, opts ParseOptions, buildTree bool,
+) (
+	parser.IDocumentContext, *antlr.CommonTokenStream, *wdlErrorListener,
+) {
+	if opts.Ambiguities != nil {
+		return parseTree(
+			inputStream,
+			opts,
+			antlr.PredictionModeLLExactAmbigDetection,
+			false,
+			buildTree,
+		)
+	}
+	tree, stream, errorListener := parseTree(
+		inputStream, opts, antlr.PredictionModeSLL, false, buildTree,
+	)
+	if errorListener.syntaxErrors != nil {
+		inputStream.Seek(0)
+		return parseTree(
+			inputStream, opts, antlr.PredictionModeLL, true, buildTree,
+		)
+	}
+	return tree, stream, errorListener
+}
 
+// walkListener walks a parse tree with a listener building a document. A parse
+// tree recovered from syntax errors may miss tokens or rules the listener
+// relies on. Should the listener still fail on one, the document is kept as
+// far as it's built and the failure is returned as an error, so that the
+// syntax errors are reported along with it.
+func walkListener(
+	listener *wdlv1_1Listener, tree antlr.Tree, recovered bool,
+) (errs []SyntaxError) {
+	if recovered {
+		defer func() {
+			if r := recover(); r != nil {
+				errs = append(errs, newSyntaxError(
+					0, 0, CodeIncomplete, fmt.Sprintf(
+						"document is incomplete after syntax errors: %v", r,
+					),
+				))
+			}
+		}()
+	}
+	antlr.ParseTreeWalkerDefault.Walk(listener, tree)
+	return nil
+}
+
+// parseStream parses a WDL document from a character stream, which is read
+// from path if path isn't empty. Every node of the document is given a new
+// NodeID.
+func parseStream(
+	path string, inputStream antlr.CharStream, opts ParseOptions,
+) (*WDL, []SyntaxError) {
+	tree, stream, errorListener := predict(inputStream, opts, true)
+	wdl := NewWDL(path, inputStream.Size())
+	if tree == nil {
+		return wdl, errorListener.syntaxErrors
+	}
+	listener := newWdlv1_1Listener(wdl)
+	errorListener.syntaxErrors = append(
+		errorListener.syntaxErrors,
+		walkListener(listener, tree, errorListener.syntaxErrors != nil)...,
+	)
+	errorListener.syntaxErrors = append(
+		errorListener.syntaxErrors, listener.literalErrors...,
+	)
+	attachComments(wdl, stream)
+	if size := inputStream.Size(); size > 0 {
+		wdl.source = inputStream.GetText(0, size-1)
+	}
+	walk(wdl, func(n node) bool {
+		n.setID(newNodeID())
+		return true
+	})
+
+	if opts.WarningsAsErrors {
+		return wdl, append(
+			errorListener.syntaxErrors, listener.warningErrors...,
+		)
+	}
 	return wdl, errorListener.syntaxErrors
 }
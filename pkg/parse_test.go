@@ -1,8 +1,12 @@
 package wdlparser
 
 import (
+	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 
+	"github.com/antlr/antlr4/runtime/Go/antlr"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 )
@@ -17,8 +21,9 @@ var commonCmpopts = cmp.Options{
 		Call{},
 		expression{},
 		value{},
+		function{},
 	),
-	cmpopts.IgnoreFields(genNode{}, "parent"),
+	cmpopts.IgnoreFields(genNode{}, "id", "parent"),
 }
 
 func TestVersion(t *testing.T) {
@@ -128,6 +133,7 @@ func TestWorkflowPrivateDeclaration(t *testing.T) {
 			name:    newIdentifier("s", false),
 			typ:     "String",
 			value:   &exprRPN{value{String, "Hello"}},
+			raw:     `"Hello"`,
 		},
 	}
 	resultPrivateDecl := result.Workflow.PrvtDecls
@@ -154,18 +160,21 @@ func TestWorkflowCall(t *testing.T) {
 				name:    newIdentifier("Greeting", false),
 				alias:   "hello",
 			},
+			Target: []string{"Greeting"},
 			Inputs: []*valueSpec{
 				{
 					genNode: genNode{start: 91, end: 113},
 					name:    newIdentifier("first_name", true),
 					typ:     "",
 					value:   &exprRPN{newIdentifier("first_name", true)},
+					raw:     "first_name",
 				},
 				{
 					genNode: genNode{start: 128, end: 144},
 					name:    newIdentifier("last_name", true),
 					typ:     "",
 					value:   &exprRPN{value{String, "Luo"}},
+					raw:     `"Luo"`,
 				},
 				{
 					genNode: genNode{start: 159, end: 161},
@@ -180,13 +189,15 @@ func TestWorkflowCall(t *testing.T) {
 				genNode: genNode{start: 174, end: 231},
 				name:    newIdentifier("Goodbye", false),
 			},
-			After: "hello",
+			Target: []string{"Goodbye"},
+			After:  []string{"hello"},
 			Inputs: []*valueSpec{
 				{
 					genNode: genNode{start: 208, end: 228},
 					name:    newIdentifier("first_name", true),
 					typ:     "",
 					value:   &exprRPN{value{String, "Yunhai"}},
+					raw:     `"Yunhai"`,
 				},
 			},
 		},
@@ -199,6 +210,70 @@ func TestWorkflowCall(t *testing.T) {
 	}
 }
 
+func TestWorkflowNamespacedCall(t *testing.T) {
+	inputPath := "testdata/call_namespace.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	expectCalls := []*Call{
+		{
+			namedNode: namedNode{
+				genNode: genNode{start: 69, end: 90},
+				name:    newIdentifier("ns.sub.Inner", false),
+				alias:   "x",
+			},
+			Target: []string{"ns", "sub", "Inner"},
+		},
+	}
+	resultCalls := result.Workflow.Calls
+	if diff := cmp.Diff(
+		expectCalls, resultCalls, commonCmpopts...,
+	); diff != "" {
+		t.Errorf("unexpected workflow calls:\n%s", diff)
+	}
+}
+
+// A call input written alone, like input: x, is a shorthand for x = x, so it
+// refers to the declaration of the same name.
+func TestCallInputShorthand(t *testing.T) {
+	result, err := Antlr4Parse(`version 1.1
+workflow W {
+    input {
+        Int x
+    }
+    call T { input: x, y = x }
+}`)
+	if err != nil {
+		t.Errorf("Found %d errors, expect no errors", len(err))
+	}
+
+	inputs := result.Workflow.Calls[0].Inputs
+	expected := []*valueSpec{
+		{
+			genNode: genNode{start: 77, end: 77},
+			name:    newIdentifier("x", true),
+			value:   &exprRPN{newIdentifier("x", true)},
+		},
+		{
+			genNode: genNode{start: 80, end: 84},
+			name:    newIdentifier("y", true),
+			value:   &exprRPN{newIdentifier("x", true)},
+			raw:     "x",
+		},
+	}
+	if diff := cmp.Diff(expected, inputs, commonCmpopts...); diff != "" {
+		t.Errorf("unexpected call inputs:\n%s", diff)
+	}
+	refs := inputs[0].value.References()
+	if len(refs) != 1 || refs[0].initialName != "x" {
+		t.Errorf("shorthand input should refer to %q: %v", "x", refs)
+	}
+}
+
 func TestWorkflowOutput(t *testing.T) {
 	inputPath := "testdata/workflow_output.wdl"
 	result, err := Antlr4Parse(inputPath)
@@ -214,6 +289,7 @@ func TestWorkflowOutput(t *testing.T) {
 			name:    newIdentifier("output_file", false),
 			typ:     "File",
 			value:   &exprRPN{value{String, "/Path/to/output"}},
+			raw:     `"/Path/to/output"`,
 		},
 	}
 	resultOutput := result.Workflow.Outputs
@@ -232,18 +308,24 @@ func TestWorkflowMeta(t *testing.T) {
 			name:    newIdentifier("author", false),
 			typ:     "",
 			value:   &exprRPN{`"Yunhai Luo"`},
+			raw:     `"Yunhai Luo"`,
+			meta:    "Yunhai Luo",
 		},
 		{
 			genNode: genNode{start: 77, end: 88},
 			name:    newIdentifier("version", false),
 			typ:     "",
 			value:   &exprRPN{"1.1"},
+			raw:     "1.1",
+			meta:    1.1,
 		},
 		{
 			genNode: genNode{start: 98, end: 112},
 			name:    newIdentifier("for", false),
 			typ:     "",
 			value:   &exprRPN{`"workflow"`},
+			raw:     `"workflow"`,
+			meta:    "workflow",
 		},
 	}
 	result, err := Antlr4Parse(inputPath)
@@ -268,6 +350,10 @@ func TestWorkflowParameterMeta(t *testing.T) {
 			name:    newIdentifier("name", false),
 			typ:     "",
 			value:   &exprRPN{`{help:"A name for workflow input"}`},
+			raw:     "{\n            help: \"A name for workflow input\"\n        }",
+			meta: map[string]interface{}{
+				"help": "A name for workflow input",
+			},
 		},
 	}
 	result, err := Antlr4Parse(inputPath)
@@ -299,6 +385,7 @@ func TestTaskInput(t *testing.T) {
 			name:    newIdentifier("name", false),
 			typ:     "String",
 			value:   &exprRPN{value{String, "World"}},
+			raw:     `"World"`,
 		},
 		{
 			genNode: genNode{start: 76, end: 95},
@@ -330,6 +417,7 @@ func TestTaskPrivateDeclaration(t *testing.T) {
 			name:    newIdentifier("s", false),
 			typ:     "String",
 			value:   &exprRPN{value{String, "Hello"}},
+			raw:     `"Hello"`,
 		},
 	}
 	resultPrivateDecl := result.Tasks[0].PrvtDecls
@@ -371,7 +459,8 @@ func TestTaskOutput(t *testing.T) {
 			genNode: genNode{start: 47, end: 73},
 			name:    newIdentifier("output_file", false),
 			typ:     "File",
-			value:   &newExpression(0, 0).rpn,
+			value:   &exprRPN{function{"stdout", 0}},
+			raw:     "stdout()",
 		},
 	}
 	resultOutput := result.Tasks[0].Outputs
@@ -382,6 +471,55 @@ func TestTaskOutput(t *testing.T) {
 	}
 }
 
+func TestDeclarationsInSourceOrder(t *testing.T) {
+	inputPath := "testdata/task_declaration_order.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+	var names []string
+	for _, decl := range result.Tasks[0].DeclarationsInSourceOrder() {
+		names = append(names, decl.name.initialName)
+	}
+	expected := []string{
+		"before_input",
+		"name",
+		"before_command",
+		"after_command",
+		"out",
+		"last",
+	}
+	if diff := cmp.Diff(expected, names); diff != "" {
+		t.Errorf("unexpected declaration order:\n%s", diff)
+	}
+}
+
+func TestBodySpan(t *testing.T) {
+	inputPath := "testdata/version1_1.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	if start, end := result.Workflow.BodySpan(); start != 33 || end != 58 {
+		t.Errorf(
+			"unexpected workflow body span: got %d-%d, expect 33-58",
+			start, end,
+		)
+	}
+	// Task header "task WriteGreeting " spans 61-79
+	if start, end := result.Tasks[0].BodySpan(); start != 80 || end != 134 {
+		t.Errorf(
+			"unexpected task body span: got %d-%d, expect 80-134",
+			start, end,
+		)
+	}
+}
+
 func TestTaskRuntime(t *testing.T) {
 	inputPath := "testdata/task_runtime.wdl"
 	expectedRuntime := []*valueSpec{
@@ -390,6 +528,7 @@ func TestTaskRuntime(t *testing.T) {
 			name:    newIdentifier("container", false),
 			typ:     "",
 			value:   &exprRPN{value{String, "ubuntu:latest"}},
+			raw:     `"ubuntu:latest"`,
 		},
 	}
 	result, err := Antlr4Parse(inputPath)
@@ -414,18 +553,24 @@ func TestTaskMeta(t *testing.T) {
 			name:    newIdentifier("author", false),
 			typ:     "",
 			value:   &exprRPN{`"Yunhai Luo"`},
+			raw:     `"Yunhai Luo"`,
+			meta:    "Yunhai Luo",
 		},
 		{
 			genNode: genNode{start: 73, end: 84},
 			name:    newIdentifier("version", false),
 			typ:     "",
 			value:   &exprRPN{"1.1"},
+			raw:     "1.1",
+			meta:    1.1,
 		},
 		{
 			genNode: genNode{start: 94, end: 104},
 			name:    newIdentifier("for", false),
 			typ:     "",
 			value:   &exprRPN{`"task"`},
+			raw:     `"task"`,
+			meta:    "task",
 		},
 	}
 	result, err := Antlr4Parse(inputPath)
@@ -450,6 +595,10 @@ func TestTaskParameterMeta(t *testing.T) {
 			name:    newIdentifier("name", false),
 			typ:     "",
 			value:   &exprRPN{`{help:"One name as task input"}`},
+			raw:     "{\n            help: \"One name as task input\"\n        }",
+			meta: map[string]interface{}{
+				"help": "One name as task input",
+			},
 		},
 	}
 	result, err := Antlr4Parse(inputPath)
@@ -465,3 +614,648 @@ func TestTaskParameterMeta(t *testing.T) {
 		t.Errorf("unexpected task parameter metadata:\n%s", diff)
 	}
 }
+
+func TestFailFast(t *testing.T) {
+	input := `version 1.1
+workflow Invalid {
+    input {
+        String
+        Int
+    }
+    call
+}`
+	_, errs := Antlr4Parse(input)
+	if len(errs) < 2 {
+		t.Fatalf("Found %d errors, expect several errors", len(errs))
+	}
+	result, errs := Antlr4ParseWithOptions(input, ParseOptions{FailFast: true})
+	if len(errs) != 1 {
+		t.Errorf("Found %d errors under FailFast, expect 1", len(errs))
+	}
+	if result.Workflow != nil {
+		t.Errorf("Found workflow under FailFast, expect no content")
+	}
+}
+
+// A syntax error is recovered from, so that another independent error later
+// in the document is reported too, even if the listener building the
+// document can't make sense of the recovered parse tree.
+func TestRecoverFromSyntaxErrors(t *testing.T) {
+	input := `version 1.1
+task A {
+    input { Int a = }
+    command <<< >>>
+}
+task B {
+    output { String b 1 }
+}`
+	result, errs := Antlr4Parse(input)
+	var positions []position
+	for _, err := range errs {
+		positions = append(positions, position{err.Line, err.Column})
+	}
+	expected := []position{{3, 20}, {7, 22}}
+	if diff := cmp.Diff(
+		expected, positions, cmp.AllowUnexported(position{}),
+	); diff != "" {
+		t.Errorf("unexpected positions of syntax errors:\n%s", diff)
+	}
+	if result == nil {
+		t.Errorf("document should still be returned")
+	}
+}
+
+// A placeholder missing its expression leaves nothing for the listener to
+// build, which is skipped without failing the listener.
+func TestEmptyPlaceholder(t *testing.T) {
+	for _, input := range []string{
+		"version 1.1\ntask A {\n    command <<< ~{} >>>\n}",
+		"version 1.1\ntask A {\n    command <<< ~{sep=' '} >>>\n}",
+		"version 1.1\ntask A {\n    String s = \"a~{}b\"\n}",
+	} {
+		_, errs := Antlr4Parse(input)
+		if len(errs) == 0 {
+			t.Errorf("Found no errors in %q, expect some", input)
+		}
+		for _, err := range errs {
+			if err.Code == CodeIncomplete {
+				t.Errorf("listener failed on %q: %s", input, err.Msg)
+			}
+		}
+	}
+}
+
+// A listener failing on a parse tree recovered from syntax errors doesn't
+// abort parsing, but its failure is reported rather than swallowed.
+func TestListenerFailureAfterSyntaxErrors(t *testing.T) {
+	input := "version 1.1\ntask A {\n    input { Int a = }\n}"
+	tree, _, errorListener := predict(
+		antlr.NewInputStream(input), ParseOptions{}, true,
+	)
+	if errorListener.syntaxErrors == nil {
+		t.Fatalf("Found no errors in %q, expect some", input)
+	}
+	// A listener without a document to build fails as soon as it builds a node.
+	errs := walkListener(newWdlv1_1Listener(nil), tree, true)
+	if len(errs) != 1 || errs[0].Code != CodeIncomplete {
+		t.Errorf("listener failure should be one error, got %v", errs)
+	}
+}
+
+func TestParseFileAndString(t *testing.T) {
+	inputPath := "testdata/version1_1.wdl"
+	fromFile, errs := ParseFile(inputPath, ParseOptions{})
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	src, err := os.ReadFile(inputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromString, errs := ParseString(string(src), ParseOptions{})
+	if errs != nil {
+		t.Errorf("Found %d errors in source, expect no errors", len(errs))
+	}
+	if fromFile.Path != inputPath || fromString.Path != "" {
+		t.Errorf(
+			"paths are %q and %q, expect %q and none",
+			fromFile.Path, fromString.Path, inputPath,
+		)
+	}
+
+	_, errs = ParseFile("testdata", ParseOptions{})
+	expected := []SyntaxError{
+		{
+			Msg:      "testdata is a directory, not a WDL document",
+			Code:     CodeUnreadable,
+			Severity: SeverityError,
+		},
+	}
+	if diff := cmp.Diff(expected, errs); diff != "" {
+		t.Errorf("unexpected syntax errors:\n%s", diff)
+	}
+	if _, errs := Antlr4Parse("testdata"); len(errs) != 1 {
+		t.Errorf("Found %d errors in a directory, expect 1", len(errs))
+	}
+}
+
+func TestParseDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"valid.wdl":       "version 1.1\nworkflow Valid {}\n",
+		"sub/invalid.wdl": "version 1.1\nworkflow Invalid {\n",
+		"sub/notes.txt":   "not a WDL document",
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	docs, errs := ParseDir(dir)
+	valid := filepath.Join(dir, "valid.wdl")
+	invalid := filepath.Join(dir, "sub", "invalid.wdl")
+	var paths []string
+	for path := range docs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if diff := cmp.Diff([]string{invalid, valid}, paths); diff != "" {
+		t.Errorf("unexpected parsed documents:\n%s", diff)
+	}
+	if docs[valid].Workflow == nil {
+		t.Errorf("workflow of %q should be parsed", valid)
+	}
+	if len(errs) != 1 || len(errs[invalid]) == 0 {
+		t.Errorf("Found errors in %v, expect errors in %q only", errs, invalid)
+	}
+}
+
+func TestPredictionFallback(t *testing.T) {
+	input := `version 1.1
+task Invalid {
+    command <<< echo >>>
+    meta { a: b }
+}`
+	_, _, ll := parseTree(
+		antlr.NewInputStream(input),
+		ParseOptions{},
+		antlr.PredictionModeLL,
+		false,
+		true,
+	)
+	_, errs := Antlr4Parse(input)
+	if len(errs) == 0 {
+		t.Fatalf("Found no errors, expect errors")
+	}
+	// Errors of the SLL pass are replaced by those of the LL pass
+	if diff := cmp.Diff(ll.syntaxErrors, errs); diff != "" {
+		t.Errorf("unexpected syntax errors:\n%s", diff)
+	}
+}
+
+func TestValidateOnly(t *testing.T) {
+	inputPath := "testdata/version1_1.wdl"
+	if errs := ValidateOnly(inputPath); errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+
+	input := `version 1.1
+task Invalid {
+    command <<< echo >>>
+    meta { a: b }
+}`
+	_, expected := Antlr4Parse(input)
+	if diff := cmp.Diff(expected, ValidateOnly(input)); diff != "" {
+		t.Errorf("unexpected syntax errors:\n%s", diff)
+	}
+}
+
+func TestBuildParseTrees(t *testing.T) {
+	input := "version 1.1 task T { command <<< echo >>> }"
+	for _, buildTree := range []bool{true, false} {
+		tree, _, _ := parseTree(
+			antlr.NewInputStream(input),
+			ParseOptions{},
+			antlr.PredictionModeSLL,
+			false,
+			buildTree,
+		)
+		if built := tree.GetChildCount() > 0; built != buildTree {
+			t.Errorf("parse tree should be built %t", buildTree)
+		}
+		if !buildTree {
+			continue
+		}
+		wdl := NewWDL("", len(input))
+		antlr.ParseTreeWalkerDefault.Walk(newWdlv1_1Listener(wdl), tree)
+		if len(wdl.Tasks) != 1 || wdl.Version != "1.1" {
+			t.Errorf("listener should build the document from the tree")
+		}
+	}
+}
+
+func TestAmbiguities(t *testing.T) {
+	inputPath := "testdata/task_command_placeholder.wdl"
+	stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(f *os.File) { os.Stderr = f }(os.Stderr)
+	os.Stderr = stderr
+	var ambiguities []string
+	_, errs := Antlr4ParseWithOptions(
+		inputPath, ParseOptions{Ambiguities: &ambiguities},
+	)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	expected := []string{
+		"reportAttemptingFullContext d=35 (expr_core), input='world }'",
+		"reportAmbiguity d=35 (expr_core): ambigAlts={11, 12}, " +
+			"input='world }\"\n    >>>\n}\n'",
+	}
+	if diff := cmp.Diff(expected, ambiguities); diff != "" {
+		t.Errorf("unexpected ambiguities:\n%s", diff)
+	}
+	stderr.Close()
+	if printed, _ := os.ReadFile(stderr.Name()); len(printed) > 0 {
+		t.Errorf("ambiguities should not be printed, got %q", printed)
+	}
+}
+
+func TestNonASCIIIdentifier(t *testing.T) {
+	input := `version 1.1
+task Tâche {
+    command <<< echo "été" >>>
+}`
+	result, errs := Antlr4Parse(input)
+	expected := []SyntaxError{
+		{
+			Line:      2,
+			Column:    6,
+			EndLine:   2,
+			EndColumn: 7,
+			Token:     "â",
+			Stop:      18,
+			Msg:       `non-ASCII letter 'â' is not allowed in identifiers`,
+			Code:      CodeNonASCII,
+			Severity:  SeverityError,
+		},
+	}
+	if diff := cmp.Diff(expected, errs); diff != "" {
+		t.Errorf("unexpected syntax errors:\n%s", diff)
+	}
+	if result == nil || len(result.Tasks) != 1 {
+		t.Errorf("task should still be parsed")
+	}
+}
+
+// WDL 1.1 has no cast, so `as` in an expression is a syntax error which
+// explains that values are coerced implicitly.
+func TestCastIsUnsupportedSyntaxError(t *testing.T) {
+	input := `version 1.1
+workflow W {
+    input {
+        Float x = 1 as Float
+    }
+}`
+	_, errs := Antlr4Parse(input)
+	expected := []SyntaxError{
+		{
+			Line:      4,
+			Column:    20,
+			EndLine:   4,
+			EndColumn: 22,
+			Token:     "as",
+			Stop:      58,
+			Msg: `"as" can't cast a value since WDL 1.1 has no cast; ` +
+				`a value is coerced to the type it's declared as`,
+			Code:     CodeCast,
+			Severity: SeverityError,
+		},
+	}
+	if diff := cmp.Diff(expected, errs); diff != "" {
+		t.Errorf("unexpected syntax errors:\n%s", diff)
+	}
+}
+
+func TestReservedKeywordAsName(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected SyntaxError
+	}{
+		{
+			"version 1.1\nworkflow W {\n    input {\n        Int command = 1" +
+				"\n    }\n}\n",
+			SyntaxError{
+				Line:      4,
+				Column:    12,
+				EndLine:   4,
+				EndColumn: 19,
+				Token:     "command",
+				Stop:      55,
+				Msg: `"command" is a reserved keyword and ` +
+					`can't be used as a name`,
+				Code:     CodeReservedKeyword,
+				Severity: SeverityError,
+			},
+		},
+		{
+			"version 1.1\nstruct S {\n    Int input\n}\n",
+			SyntaxError{
+				Line:      3,
+				Column:    8,
+				EndLine:   3,
+				EndColumn: 13,
+				Token:     "input",
+				Stop:      35,
+				Msg: `"input" is a reserved keyword and ` +
+					`can't be used as a name`,
+				Code:     CodeReservedKeyword,
+				Severity: SeverityError,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		_, errs := Antlr4Parse(tc.input)
+		if diff := cmp.Diff([]SyntaxError{tc.expected}, errs); diff != "" {
+			t.Errorf("unexpected syntax errors:\n%s", diff)
+		}
+	}
+}
+
+func TestVersionStatement(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected SyntaxError
+	}{
+		{
+			"workflow W {}\n",
+			SyntaxError{
+				Line:      1,
+				Column:    0,
+				EndLine:   1,
+				EndColumn: 8,
+				Token:     "workflow",
+				Stop:      7,
+				Msg: `a document must start with a version statement, ` +
+					`like "version 1.1"`,
+				Code:     CodeVersion,
+				Severity: SeverityError,
+			},
+		},
+		{
+			"import \"lib.wdl\"\nversion 1.1\nworkflow W {}\n",
+			SyntaxError{
+				Line:      2,
+				Column:    0,
+				EndLine:   2,
+				EndColumn: 7,
+				Token:     "version",
+				Stop:      23,
+				Msg: `version statement must be the first statement, ` +
+					`but "import" at line 1:0 comes before it`,
+				Code:     CodeVersion,
+				Severity: SeverityError,
+			},
+		},
+		{
+			"version 1.1\nversion 1.1\nworkflow W {}\n",
+			SyntaxError{
+				Line:      2,
+				Column:    0,
+				EndLine:   2,
+				EndColumn: 7,
+				Token:     "version",
+				Stop:      18,
+				Msg: "a document can only have one version statement, " +
+					"which is at line 1:0",
+				Code:     CodeVersion,
+				Severity: SeverityError,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		_, errs := Antlr4Parse(tc.input)
+		if diff := cmp.Diff([]SyntaxError{tc.expected}, errs); diff != "" {
+			t.Errorf("unexpected syntax errors:\n%s", diff)
+		}
+	}
+}
+
+func TestDuplicateWorkflow(t *testing.T) {
+	input := `version 1.1
+workflow First {}
+workflow Second {}
+`
+	result, errs := Antlr4Parse(input)
+	expected := []SyntaxError{
+		{
+			Line:      3,
+			Column:    0,
+			EndLine:   3,
+			EndColumn: 8,
+			Token:     "workflow",
+			Stop:      37,
+			Msg: "a document can only have one workflow, " +
+				"which is at line 2:0",
+			Code:     CodeDuplicateWorkflow,
+			Severity: SeverityError,
+		},
+	}
+	if diff := cmp.Diff(expected, errs); diff != "" {
+		t.Errorf("unexpected syntax errors:\n%s", diff)
+	}
+	if result.Workflow == nil || result.Workflow.name.initialName != "First" {
+		t.Errorf("the first workflow should be kept")
+	}
+}
+
+func TestLiteralOutOfRange(t *testing.T) {
+	input := "version 1.1\nworkflow W { Int x = 99999999999999999999 }"
+	result, errs := ParseString(input, ParseOptions{})
+	expected := []SyntaxError{
+		{
+			Line:      2,
+			Column:    21,
+			EndLine:   2,
+			EndColumn: 41,
+			Token:     "99999999999999999999",
+			Stop:      52,
+			Msg: "Int literal 99999999999999999999 is invalid: " +
+				"value out of range",
+			Code:     CodeLiteral,
+			Severity: SeverityError,
+		},
+	}
+	if diff := cmp.Diff(expected, errs); diff != "" {
+		t.Errorf("unexpected syntax errors:\n%s", diff)
+	}
+	if result.Workflow == nil || len(result.Workflow.PrvtDecls) != 1 {
+		t.Errorf("the declaration with the literal should be kept")
+	}
+}
+
+func TestWorkflowOutputSection(t *testing.T) {
+	testCases := []struct {
+		body    string
+		has     bool
+		outputs int
+	}{
+		{"", false, 0},
+		{"output {}", true, 0},
+		{`output { String s = "a" }`, true, 1},
+	}
+	for _, tc := range testCases {
+		input := "version 1.1 workflow W {" + tc.body + "}"
+		result, errs := Antlr4Parse(input)
+		if errs != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(errs), input,
+			)
+			continue
+		}
+		w := result.Workflow
+		if w.HasOutputSection != tc.has || len(w.Outputs) != tc.outputs {
+			t.Errorf(
+				"%q has output section %t and %d outputs, expect %t and %d",
+				tc.body, w.HasOutputSection, len(w.Outputs),
+				tc.has, tc.outputs,
+			)
+		}
+	}
+}
+
+func TestSource(t *testing.T) {
+	input := `version 1.1
+task T {
+    input {
+        String greeting = "héllo"
+        Int n
+    }
+    command <<< echo ~{greeting} >>>
+}`
+	result, errs := Antlr4Parse(input)
+	if errs != nil {
+		t.Errorf("Found %d errors, expect no errors", len(errs))
+	}
+	task := result.Tasks[0]
+	testCases := []struct {
+		n    Node
+		want string
+	}{
+		{task.Inputs[0], `String greeting = "héllo"`},
+		{task.Inputs[1], "Int n"},
+		{task.CommandParts()[1].(*expression), "greeting"},
+		{result, input},
+	}
+	for _, tc := range testCases {
+		if diff := cmp.Diff(tc.want, result.Source(tc.n)); diff != "" {
+			t.Errorf("unexpected source of %T:\n%s", tc.n, diff)
+		}
+	}
+	if src := NewWDL("", 0).Source(task); src != "" {
+		t.Errorf("source of a node of another document should be empty")
+	}
+}
+
+func TestNonASCIIPositions(t *testing.T) {
+	input := `version 1.1
+# 注释 with ünïcode
+task T {
+    input {
+        String s = "日本語 é"
+        Int n = 1
+    }
+    command <<< echo ~{s} >>>
+    meta {
+        description: "描述 café"
+    }
+}`
+	result, errs := Antlr4Parse(input)
+	if errs != nil {
+		t.Errorf("Found %d errors, expect no errors", len(errs))
+	}
+	task := result.Tasks[0]
+	if diff := cmp.Diff(
+		[]string{"# 注释 with ünïcode"}, task.LeadingComments(),
+	); diff != "" {
+		t.Errorf("unexpected comments:\n%s", diff)
+	}
+	if task.Meta[0].meta != "描述 café" {
+		t.Errorf("unexpected description: %v", task.Meta[0].meta)
+	}
+
+	runes := []rune(input)
+	for _, decl := range []*valueSpec{
+		task.Inputs[0], task.Inputs[1], task.Meta[0],
+	} {
+		// Positions are offsets in characters
+		text := string(runes[decl.getStart() : decl.getEnd()+1])
+		if text != result.Source(decl) {
+			t.Errorf("%q is at [%d, %d] of %q", result.Source(decl),
+				decl.getStart(), decl.getEnd(), text)
+		}
+		start, end := result.ByteSpan(decl)
+		if input[start:end] != text {
+			t.Errorf("%q is at bytes [%d, %d) of %q",
+				text, start, end, input[start:end])
+		}
+	}
+	if src := result.Source(task.Inputs[1]); src != "Int n = 1" {
+		t.Errorf("unexpected source of input: %q", src)
+	}
+}
+
+func TestParent(t *testing.T) {
+	for _, inputPath := range []string{
+		"testdata/workflow_scatter.wdl",
+		"testdata/workflow_call.wdl",
+		"testdata/task_input.wdl",
+		"testdata/task_meta.wdl",
+		"testdata/struct.wdl",
+		"testdata/import.wdl",
+	} {
+		result, err := Antlr4Parse(inputPath)
+		if err != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(err), inputPath,
+			)
+		}
+		walk(result, func(n node) bool {
+			for _, child := range children(n) {
+				if child.getParent() != n {
+					t.Errorf(
+						"%T at %d in %q has parent %T, expect %T",
+						child, child.getStart(), inputPath,
+						child.getParent(), n,
+					)
+				}
+			}
+			return true
+		})
+	}
+
+	result, _ := Antlr4Parse("testdata/task_input.wdl")
+	var n node = result.Tasks[0].Inputs[0]
+	for _, expected := range []node{result.Tasks[0], result} {
+		n = n.getParent()
+		if n != expected {
+			t.Errorf("unexpected parent %T, expect %T", n, expected)
+		}
+	}
+}
+
+func TestOffendingToken(t *testing.T) {
+	testCases := []struct {
+		input string
+		token string
+		stop  int
+	}{
+		{"version 1.1\nworkflow W {}\n}", "}", 26},
+		{"version 1.1\nworkflow W {", "", 23},
+	}
+	for _, tc := range testCases {
+		_, errs := ParseString(tc.input, ParseOptions{})
+		if len(errs) == 0 {
+			t.Fatalf("Found no errors in %q, expect some", tc.input)
+		}
+		if errs[0].Token != tc.token || errs[0].Stop != tc.stop {
+			t.Errorf(
+				"offending token of %q is %q stopping at %d, expect %q at %d",
+				tc.input, errs[0].Token, errs[0].Stop, tc.token, tc.stop,
+			)
+		}
+	}
+}
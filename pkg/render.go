@@ -0,0 +1,138 @@
+package wdlparser
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// bindValue converts a value decoded from JSON, like one in an inputs JSON,
+// into a WDL value of a type. Maps are not supported yet.
+func bindValue(typ Type, j interface{}) (value, error) {
+	if typ == nil {
+		return value{}, fmt.Errorf("cannot bind %v to an unsupported type", j)
+	}
+	switch t := typ.(type) {
+	case optional:
+		if j == nil {
+			return value{typ, nil}, nil
+		}
+		return bindValue(t.base, j)
+	case primitive:
+		switch g := j.(type) {
+		case bool:
+			if t == Boolean {
+				return value{Boolean, g}, nil
+			}
+		case float64:
+			if t == Int && g == math.Trunc(g) {
+				return value{Int, int64(g)}, nil
+			}
+			if t == Float {
+				return value{Float, g}, nil
+			}
+		case string:
+			if t == String || t == File || t == Directory {
+				return value{t, g}, nil
+			}
+		}
+	case array:
+		if elems, ok := j.([]interface{}); ok {
+			values := make([]value, 0, len(elems))
+			for _, elem := range elems {
+				v, err := bindValue(t.elem, elem)
+				if err != nil {
+					return value{}, err
+				}
+				values = append(values, v)
+			}
+			return value{typ, values}, nil
+		}
+	}
+	return value{}, fmt.Errorf("cannot bind %v to %s", j, typ.typeString())
+}
+
+// evaluate computes the value bound to a declaration and coerces it to the
+// declared type, so that a File declared with a string literal holds a File.
+// A value of a type which values don't model, like a struct, is kept as it's
+// evaluated.
+func (v *valueSpec) evaluate(env map[string]value) (value, error) {
+	val, err := v.value.evaluate(env)
+	if err != nil {
+		return val, err
+	}
+	typ := parseType(v.typ)
+	if typ == nil {
+		return val, nil
+	}
+	return val.CoerceTo(typ)
+}
+
+// RenderCommand evaluates placeholders in the command of a task with inputs,
+// like those decoded from an inputs JSON, and returns the concrete command.
+// The task may be left empty if the document has only one task. An input is
+// looked up qualified by the task name, like Task.input, or by its name alone.
+// Inputs not given take their defaults, or None if optional, and private
+// declarations are evaluated before the command.
+func (w *WDL) RenderCommand(
+	task string, inputs map[string]interface{},
+) (string, error) {
+	var t *Task
+	for _, candidate := range w.Tasks {
+		if candidate.name.initialName == task ||
+			task == "" && len(w.Tasks) == 1 {
+			t = candidate
+		}
+	}
+	if t == nil {
+		return "", fmt.Errorf("no task %q in %q", task, w.Path)
+	}
+
+	env := map[string]value{}
+	for _, input := range t.Inputs {
+		name := input.name.initialName
+		typ := parseType(input.typ)
+		j, ok := inputs[t.name.initialName+"."+name]
+		if !ok {
+			j, ok = inputs[name]
+		}
+		var v value
+		var err error
+		switch _, optional := typ.(optional); {
+		case ok:
+			v, err = bindValue(typ, j)
+		case len(*input.value) > 0:
+			v, err = input.evaluate(env)
+		case optional:
+			v = value{typ, nil}
+		default:
+			err = fmt.Errorf("missing required input %q", name)
+		}
+		if err != nil {
+			return "", err
+		}
+		env[name] = v
+	}
+	for _, decl := range t.PrvtDecls {
+		v, err := decl.evaluate(env)
+		if err != nil {
+			return "", err
+		}
+		env[decl.name.initialName] = v
+	}
+
+	var command strings.Builder
+	for _, part := range t.commandParts {
+		switch part := part.(type) {
+		case string:
+			command.WriteString(part)
+		case *expression:
+			v, err := part.evaluate(env)
+			if err != nil {
+				return "", err
+			}
+			command.WriteString(stringify(v))
+		}
+	}
+	return command.String(), nil
+}
@@ -0,0 +1,101 @@
+package wdlparser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderCommand(t *testing.T) {
+	input := `version 1.1
+task T {
+    input {
+        String name
+        Int? threads
+        Array[String] flags = ["-v"]
+    }
+    String label = name + "!"
+    Int? y = 3
+    Int? z = y
+    command <<<
+        run ~{sep=" " flags} --name ~{label} ~{default="1" threads} -z ~{z}
+    >>>
+}`
+	result, errs := Antlr4Parse(input)
+	if errs != nil {
+		t.Errorf("Found %d errors, expect no errors", len(errs))
+	}
+	testCases := []struct {
+		inputs map[string]interface{}
+		want   string
+		err    string
+	}{
+		{
+			map[string]interface{}{"T.name": "a", "threads": float64(4)},
+			"\n        run -v --name a! 4 -z 3\n    ",
+			"",
+		},
+		{
+			map[string]interface{}{
+				"name": "b", "flags": []interface{}{"-q", "-x"},
+			},
+			"\n        run -q -x --name b! 1 -z 3\n    ",
+			"",
+		},
+		{nil, "", `missing required input "name"`},
+		{
+			map[string]interface{}{"name": float64(1)},
+			"",
+			"cannot bind 1 to String",
+		},
+	}
+	for _, tc := range testCases {
+		got, err := result.RenderCommand("T", tc.inputs)
+		if err != nil && err.Error() != tc.err ||
+			err == nil && tc.err != "" {
+			t.Errorf("error should be %q is %v", tc.err, err)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("unexpected command:\n%s", diff)
+		}
+	}
+}
+
+func TestEvaluateDeclaration(t *testing.T) {
+	input := `version 1.1
+task T {
+    input {
+        String name = "a"
+    }
+    File f = name + ".txt"
+    Directory d = "data/"
+    Array[File] fs = [f, "b.txt"]
+    Float x = 1
+    command <<< >>>
+}`
+	result, errs := Antlr4Parse(input)
+	if errs != nil {
+		t.Fatalf("Found %d errors, expect no errors", len(errs))
+	}
+	env := map[string]value{"name": {String, "a"}}
+	var got []value
+	for _, decl := range result.Tasks[0].PrvtDecls {
+		v, err := decl.evaluate(env)
+		if err != nil {
+			t.Errorf("failed to evaluate %q: %v", decl.name.initialName, err)
+		}
+		env[decl.name.initialName] = v
+		got = append(got, v)
+	}
+	expected := []value{
+		{File, "a.txt"},
+		{Directory, "data/"},
+		{ArrayOf(File), []value{{File, "a.txt"}, {File, "b.txt"}}},
+		{Float, 1.0},
+	}
+	if diff := cmp.Diff(
+		expected, got, cmp.AllowUnexported(value{}, array{}),
+	); diff != "" {
+		t.Errorf("unexpected values of declarations:\n%s", diff)
+	}
+}
@@ -0,0 +1,74 @@
+package wdlparser
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+)
+
+// An Edit replaces characters from Start up to but excluding End, both 0-based
+// positions in the source of a parsed document, with Text.
+type Edit struct {
+	Start, End int
+	Text       string
+}
+
+// nodeKey identifies a node by its concrete type and span.
+type nodeKey struct {
+	typ        string
+	start, end int
+}
+
+func keyOf(n node, start, end int) nodeKey {
+	return nodeKey{fmt.Sprintf("%T", n), start, end}
+}
+
+// Reparse applies an edit to the source of a parsed document and parses the
+// edited source into a new document. Nodes of the new document lying wholly
+// before or after the edited region keep the NodeIDs of their counterparts in
+// the old document, and imports among them keep their resolved documents.
+// Other nodes, including those enclosing the edit, are given new NodeIDs.
+// The whole edited source is parsed for now.
+func (w *WDL) Reparse(edit Edit) (*WDL, []SyntaxError) {
+	src := []rune(w.source)
+	if edit.Start < 0 || edit.Start > edit.End || edit.End > len(src) {
+		return nil, []SyntaxError{newSyntaxError(
+			0, 0, CodeInvalidEdit, fmt.Sprintf(
+				"edit [%d, %d) is out of the source of %d characters",
+				edit.Start, edit.End, len(src),
+			),
+		)}
+	}
+	edited := string(src[:edit.Start]) + edit.Text + string(src[edit.End:])
+	reparsed, errs := parseStream(
+		w.Path, antlr.NewInputStream(edited), ParseOptions{},
+	)
+
+	old := map[nodeKey]node{}
+	walk(w, func(n node) bool {
+		old[keyOf(n, n.getStart(), n.getEnd())] = n
+		return true
+	})
+	inserted := edit.Start + utf8.RuneCountInString(edit.Text)
+	shift := edit.End - inserted
+	walk(reparsed, func(n node) bool {
+		var key nodeKey
+		switch {
+		case n.getEnd() < edit.Start:
+			key = keyOf(n, n.getStart(), n.getEnd())
+		case n.getStart() >= inserted:
+			key = keyOf(n, n.getStart()+shift, n.getEnd()+shift)
+		default:
+			return true
+		}
+		if o, ok := old[key]; ok {
+			n.setID(o.ID())
+			if is, ok := n.(*importSpec); ok {
+				is.wdl = o.(*importSpec).wdl
+			}
+		}
+		return true
+	})
+	return reparsed, errs
+}
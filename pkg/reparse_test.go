@@ -0,0 +1,63 @@
+package wdlparser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReparse(t *testing.T) {
+	inputPath := "testdata/task_input.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	ids := map[NodeID]bool{}
+	walk(result, func(n node) bool {
+		if ids[n.ID()] {
+			t.Errorf("NodeID %d is given to more than one node", n.ID())
+		}
+		ids[n.ID()] = true
+		return true
+	})
+
+	task := result.Tasks[0]
+	last := task.Inputs[len(task.Inputs)-1]
+	insertAt := last.getEnd() + 1
+	reparsed, errs := result.Reparse(
+		Edit{insertAt, insertAt, "\n        Int threads = 4"},
+	)
+	if errs != nil {
+		t.Fatalf("Found %d errors after the edit, expect no errors", len(errs))
+	}
+
+	newTask := reparsed.Tasks[0]
+	if len(newTask.Inputs) != len(task.Inputs)+1 {
+		t.Fatalf(
+			"task has %d inputs after the edit, expect %d",
+			len(newTask.Inputs), len(task.Inputs)+1,
+		)
+	}
+	added := newTask.Inputs[len(newTask.Inputs)-1]
+	if added.name.initialName != "threads" || ids[added.ID()] {
+		t.Errorf(
+			"input %q with NodeID %d should be new input \"threads\"",
+			added.name.initialName, added.ID(),
+		)
+	}
+	for i, input := range task.Inputs {
+		if newTask.Inputs[i].ID() != input.ID() {
+			t.Errorf(
+				"NodeID of unchanged input %q changed from %d to %d",
+				input.name.initialName, input.ID(), newTask.Inputs[i].ID(),
+			)
+		}
+	}
+	if newTask.ID() == task.ID() {
+		t.Errorf("NodeID of the edited task should change")
+	}
+	if !strings.Contains(reparsed.source, "Int threads = 4") {
+		t.Errorf("source should be edited is %q", reparsed.source)
+	}
+}
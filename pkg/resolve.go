@@ -0,0 +1,595 @@
+package wdlparser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// namespace returns the name an import is referred to in the importing
+// document, which is its alias if given or the imported file name otherwise.
+func (is *importSpec) namespace() string {
+	if is.alias != "" {
+		return is.alias
+	}
+	return is.name.initialName
+}
+
+// importPath returns the location of the imported document, resolving a
+// relative URI against the directory of the importing document.
+func (is *importSpec) importPath(from string) (string, error) {
+	uri := is.uri.literal()
+	switch {
+	case strings.HasPrefix(uri, "http://"), strings.HasPrefix(uri, "https://"):
+		return "", fmt.Errorf("remote import %q is not supported", uri)
+	case strings.HasPrefix(uri, "file://"):
+		return strings.TrimPrefix(uri, "file://"), nil
+	case filepath.IsAbs(uri):
+		return uri, nil
+	}
+	return filepath.Join(filepath.Dir(from), uri), nil
+}
+
+// ResolveImports parses every document imported by w, recursively, and links
+// each parsed document to its import statement. A document imported more than
+// once, directly or transitively, is parsed only once.
+func (w *WDL) ResolveImports() error {
+	return w.resolveImports(map[string]*WDL{})
+}
+
+func (w *WDL) resolveImports(resolved map[string]*WDL) error {
+	if abs, err := filepath.Abs(w.Path); err == nil {
+		resolved[abs] = w
+	}
+	for _, is := range w.Imports {
+		p, err := is.importPath(w.Path)
+		if err != nil {
+			return err
+		}
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+		if doc, ok := resolved[abs]; ok {
+			is.wdl = doc
+			continue
+		}
+		if info, err := os.Stat(p); err != nil || info.IsDir() {
+			return fmt.Errorf("cannot import %q: not a file", p)
+		}
+		doc, errs := Antlr4Parse(p)
+		if errs != nil {
+			return fmt.Errorf(
+				"cannot import %q: found %d syntax errors", p, len(errs),
+			)
+		}
+		is.wdl = doc
+		if err := doc.resolveImports(resolved); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AllImportedDocuments returns every document imported by w, directly or
+// transitively, once each and ordered so that a document comes after all
+// documents it imports. Imports must be resolved beforehand.
+func (w *WDL) AllImportedDocuments() ([]*WDL, error) {
+	var docs []*WDL
+	visited := map[*WDL]bool{w: true}
+	var visit func(doc *WDL) error
+	visit = func(doc *WDL) error {
+		for _, is := range doc.Imports {
+			if is.wdl == nil {
+				return fmt.Errorf("import %q is not resolved", is.namespace())
+			}
+			if visited[is.wdl] {
+				continue
+			}
+			visited[is.wdl] = true
+			if err := visit(is.wdl); err != nil {
+				return err
+			}
+			docs = append(docs, is.wdl)
+		}
+		return nil
+	}
+	if err := visit(w); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// structs returns all structs usable in a document by name, which are the
+// ones it defines and the ones its resolved imports provide, renamed by import
+// aliases if any.
+func (w *WDL) structs() map[string]*Struct {
+	return w.collectStructs(map[*WDL]bool{})
+}
+
+func (w *WDL) collectStructs(visited map[*WDL]bool) map[string]*Struct {
+	structs := map[string]*Struct{}
+	if visited[w] {
+		return structs
+	}
+	visited[w] = true
+	for _, is := range w.Imports {
+		if is.wdl == nil {
+			continue
+		}
+		for name, s := range is.wdl.collectStructs(visited) {
+			if alias, ok := is.importAliases[name]; ok {
+				name = alias
+			}
+			structs[name] = s
+		}
+	}
+	for _, s := range w.Structs {
+		structs[s.name.initialName] = s
+	}
+	return structs
+}
+
+// ResolveStruct finds the struct a declaration is typed with, like Foo for
+// Foo s or Foo? s, which may be imported and renamed by an import alias.
+// Imports must be resolved beforehand to find imported structs.
+func (w *WDL) ResolveStruct(decl *valueSpec) (*Struct, error) {
+	name := strings.TrimSuffix(strings.TrimSpace(decl.typ), "?")
+	if s, ok := w.structs()[name]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf(
+		"type %q of %q is not a known struct", decl.typ, decl.name.initialName,
+	)
+}
+
+// Member returns the member of a struct with a name, if there is one.
+func (s *Struct) Member(name string) (*valueSpec, bool) {
+	for _, member := range s.Members {
+		if member.name.initialName == name {
+			return member, true
+		}
+	}
+	return nil, false
+}
+
+// A qualifiedName is a dotted name of an entity in an imported document, like
+// lib.Align, split into the namespaces leading to the document and the name of
+// the entity in it. A name without namespaces is in the document itself.
+type qualifiedName struct {
+	namespaces []string // outermost first
+	name       string
+}
+
+func (q qualifiedName) String() string {
+	return strings.Join(
+		append(append([]string(nil), q.namespaces...), q.name), ".",
+	)
+}
+
+// qualifiedName returns the target of a call as a qualified name. It returns
+// an error if the call has no target, like one recovered from a syntax error.
+func (c *Call) qualifiedName() (qualifiedName, error) {
+	n := len(c.Target)
+	if n == 0 {
+		return qualifiedName{}, fmt.Errorf("call has no target")
+	}
+	return qualifiedName{c.Target[:n-1], c.Target[n-1]}, nil
+}
+
+// importNamed returns the import of a document referred to by a namespace,
+// which is its alias or its file name, or nil if there is none.
+func (w *WDL) importNamed(ns string) *importSpec {
+	for _, is := range w.Imports {
+		if is.namespace() == ns {
+			return is
+		}
+	}
+	return nil
+}
+
+// importedDocument finds the document a chain of namespaces refers to, where
+// every namespace is looked up in the imports of the document found by the
+// previous namespace.
+func (w *WDL) importedDocument(namespaces []string) (*WDL, error) {
+	doc := w
+	for _, ns := range namespaces {
+		is := doc.importNamed(ns)
+		if is == nil {
+			return nil, fmt.Errorf("unknown namespace %q", ns)
+		}
+		if is.wdl == nil {
+			return nil, fmt.Errorf("import %q is not resolved", ns)
+		}
+		doc = is.wdl
+	}
+	return doc, nil
+}
+
+// ResolveCall finds the task or workflow a call targets. Every namespace in
+// a dotted call target is looked up in the imports of the document found by
+// the previous namespace, so imports must be resolved beforehand.
+func (w *WDL) ResolveCall(c *Call) (node, error) {
+	target, err := c.qualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	doc, err := w.importedDocument(target.namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	callee := target.name
+	for _, t := range doc.Tasks {
+		if t.name.initialName == callee {
+			return t, nil
+		}
+	}
+	if doc != w && doc.Workflow != nil &&
+		doc.Workflow.name.initialName == callee {
+		return doc.Workflow, nil
+	}
+	return nil, fmt.Errorf("cannot resolve call to %q", c.name.initialName)
+}
+
+// CalledTasks returns targets of calls in a workflow as they're written, like
+// Align or lib.Align, in the order they're first called. A target may be a
+// workflow of an imported document, which can only be told apart from a task
+// once imports are resolved; see ResolveCalledTasks.
+func (w *Workflow) CalledTasks() []string {
+	var targets []string
+	seen := map[string]bool{}
+	for _, c := range w.Calls {
+		target := strings.Join(c.Target, ".")
+		if target != "" && !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// ResolveCalledTasks returns tasks called by a workflow, directly or through
+// workflows it calls, in the order they're first called. Imports must be
+// resolved beforehand, and an error is returned if a call can't be resolved.
+func (w *Workflow) ResolveCalledTasks() ([]*Task, error) {
+	var tasks []*Task
+	seen := map[node]bool{}
+	var visit func(w *Workflow) error
+	visit = func(w *Workflow) error {
+		doc := documentOf(w)
+		if doc == nil {
+			return fmt.Errorf(
+				"workflow %q is in no document", w.name.initialName,
+			)
+		}
+		for _, c := range w.Calls {
+			callee, err := doc.ResolveCall(c)
+			if err != nil {
+				return err
+			}
+			if seen[callee] {
+				continue
+			}
+			seen[callee] = true
+			switch callee := callee.(type) {
+			case *Task:
+				tasks = append(tasks, callee)
+			case *Workflow:
+				if err := visit(callee); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := visit(w); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// QualifiedCallOutputs returns outputs of calls in a workflow by their fully
+// qualified names, like Workflow.call.output, where a call is named by its
+// alias or callee. Calls which can't be resolved are left out, so imports
+// must be resolved beforehand to include calls to imported documents.
+func (w *Workflow) QualifiedCallOutputs() map[string]*valueSpec {
+	outputs := map[string]*valueSpec{}
+	doc := documentOf(w)
+	if doc == nil {
+		return outputs
+	}
+	for _, c := range w.Calls {
+		callee, err := doc.ResolveCall(c)
+		if err != nil {
+			continue
+		}
+		var calleeOutputs []*valueSpec
+		switch callee := callee.(type) {
+		case *Task:
+			calleeOutputs = callee.Outputs
+		case *Workflow:
+			calleeOutputs = callee.Outputs
+		}
+		prefix := w.name.initialName + "." + c.callName() + "."
+		for _, output := range calleeOutputs {
+			outputs[prefix+output.name.initialName] = output
+		}
+	}
+	return outputs
+}
+
+// Resolve finds what a name refers to from a node of w, along with the
+// document it's defined in. A name can be a declaration visible from the node,
+// the variable of a scatter enclosing the node, which resolves to the scatter,
+// an output of a call like call.output, or a task, workflow or struct, which
+// may be in an imported document like ns.Task or, for a struct, imported by
+// its name or alias. Declarations take precedence, so an input named like a
+// struct shadows the struct. Imports must be resolved beforehand to resolve
+// names in imported documents.
+func (w *WDL) Resolve(name string, from node) (node, *WDL, error) {
+	segments := strings.Split(name, ".")
+	switch container := enclosing(from).(type) {
+	case *Task:
+		decls := append(append(
+			append([]*valueSpec(nil), container.Inputs...),
+			container.PrvtDecls...),
+			container.Outputs...,
+		)
+		for _, decl := range decls {
+			if decl.name.initialName == name {
+				return decl, w, nil
+			}
+		}
+	case *Workflow:
+		decls := append(
+			append([]*valueSpec(nil), container.Inputs...),
+			container.PrvtDecls...,
+		)
+		for _, decl := range decls {
+			if decl.name.initialName == name {
+				return decl, w, nil
+			}
+		}
+		for n := from.getParent(); n != nil; n = n.getParent() {
+			if s, ok := n.(*Scatter); ok && s.Variable == name {
+				return s, w, nil
+			}
+		}
+		for _, c := range container.Calls {
+			callName := c.callName()
+			if len(segments) != 2 || segments[0] != callName {
+				continue
+			}
+			callee, err := w.ResolveCall(c)
+			if err != nil {
+				return nil, nil, err
+			}
+			doc := documentOf(callee)
+			var outputs []*valueSpec
+			switch callee := callee.(type) {
+			case *Task:
+				outputs = callee.Outputs
+			case *Workflow:
+				outputs = callee.Outputs
+			}
+			for _, output := range outputs {
+				if output.name.initialName == segments[1] {
+					return output, doc, nil
+				}
+			}
+			return nil, nil, fmt.Errorf(
+				"call %q has no output %q", callName, segments[1],
+			)
+		}
+	}
+
+	doc, err := w.importedDocument(segments[:len(segments)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := segments[len(segments)-1]
+	for _, t := range doc.Tasks {
+		if t.name.initialName == last {
+			return t, doc, nil
+		}
+	}
+	for _, s := range doc.Structs {
+		if s.name.initialName == last {
+			return s, doc, nil
+		}
+	}
+	if doc.Workflow != nil && doc.Workflow.name.initialName == last {
+		return doc.Workflow, doc, nil
+	}
+	if s, ok := w.structs()[name]; ok && len(segments) == 1 {
+		return s, documentOf(s), nil
+	}
+	return nil, nil, fmt.Errorf("cannot resolve %q", name)
+}
+
+// IsConstant reports whether the value bound to a declaration or a runtime
+// attribute is known before any input is given, like 4 * 1024 or "~{cpus} GB"
+// where cpus is a private declaration of a constant value. A value referring
+// to an input, to a scatter variable or to an output of a call isn't
+// constant, nor is an input, whose default may be overridden, or a
+// declaration without a value.
+func (v *valueSpec) IsConstant() bool {
+	return v.isConstant(map[*valueSpec]bool{})
+}
+
+// isConstant is IsConstant where a declaration referring to itself through
+// others, which are seen, isn't constant.
+func (v *valueSpec) isConstant(seen map[*valueSpec]bool) bool {
+	if v.value == nil || len(*v.value) == 0 || seen[v] {
+		return false
+	}
+	seen[v] = true
+	defer delete(seen, v)
+	doc := documentOf(v)
+	if doc == nil {
+		return !v.value.hasReference()
+	}
+	var inputs, prvtDecls []*valueSpec
+	switch container := enclosing(v).(type) {
+	case *Task:
+		inputs, prvtDecls = container.Inputs, container.PrvtDecls
+	case *Workflow:
+		inputs, prvtDecls = container.Inputs, container.PrvtDecls
+	}
+	if containsValueSpec(inputs, v) {
+		return false
+	}
+	for _, ref := range v.value.References() {
+		n, _, err := doc.Resolve(ref.initialName, v)
+		if err != nil {
+			return false
+		}
+		if _, ok := n.(*Scatter); ok {
+			return false // the variable takes each element in turn
+		}
+		decl, ok := n.(*valueSpec)
+		if !ok || !containsValueSpec(prvtDecls, decl) ||
+			!decl.isConstant(seen) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsValueSpec reports whether a declaration is one of decls.
+func containsValueSpec(decls []*valueSpec, decl *valueSpec) bool {
+	for _, d := range decls {
+		if d == decl {
+			return true
+		}
+	}
+	return false
+}
+
+// documentOf returns the document a node belongs to.
+func documentOf(n node) *WDL {
+	for ; n != nil; n = n.getParent() {
+		if doc, ok := n.(*WDL); ok {
+			return doc
+		}
+	}
+	return nil
+}
+
+// RecursiveStruct is the rule name of diagnostics on structs which contain
+// themselves.
+const RecursiveStruct = "RecursiveStruct"
+
+// requiredStructs returns names in a raw WDL type which a value of the type
+// can't be without, like Foo in Pair[Foo,Int] or Array[Foo]+. Names under an
+// optional type, a possibly empty array or a map are not required.
+func requiredStructs(rawType string) []string {
+	rawType = strings.TrimSpace(rawType)
+	switch {
+	case strings.HasSuffix(rawType, "?"):
+		return nil
+	case strings.HasPrefix(rawType, "Array["):
+		if !strings.HasSuffix(rawType, "]+") {
+			return nil
+		}
+		return requiredStructs(rawType[len("Array[") : len(rawType)-2])
+	case strings.HasPrefix(rawType, "Map["):
+		return nil
+	case strings.HasPrefix(rawType, "Pair[") && strings.HasSuffix(rawType, "]"):
+		inner := rawType[len("Pair[") : len(rawType)-1]
+		depth := 0
+		for i, r := range inner {
+			switch r {
+			case '[':
+				depth++
+			case ']':
+				depth--
+			case ',':
+				if depth == 0 {
+					return append(
+						requiredStructs(inner[:i]),
+						requiredStructs(inner[i+1:])...,
+					)
+				}
+			}
+		}
+		return nil
+	}
+	return []string{rawType}
+}
+
+// CheckStructCycles finds structs which contain themselves, directly or
+// through other structs, so that no value of them can ever be built. A struct
+// referring to itself through an optional member, like `Node? next`, is fine.
+// Imports should be resolved beforehand so that imported structs are known.
+// Member types are looked up in the document of their struct, where an
+// imported struct may be renamed by an import alias, and structs in a cycle
+// are named as they are defined. Every cycle is reported once, at the first
+// struct of it in the document.
+func (w *WDL) CheckStructCycles() []error {
+	scopes := map[node]map[string]*Struct{}
+	scope := func(s *Struct) map[string]*Struct {
+		doc := s.getParent()
+		if _, ok := scopes[doc]; !ok {
+			if d, ok := doc.(*WDL); ok {
+				scopes[doc] = d.structs()
+			} else {
+				scopes[doc] = w.structs()
+			}
+		}
+		return scopes[doc]
+	}
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[*Struct]int{}
+	var errs []error
+	var path []*Struct
+	var visit func(s *Struct)
+	visit = func(s *Struct) {
+		state[s] = visiting
+		path = append(path, s)
+		for _, member := range s.Members {
+			for _, name := range requiredStructs(member.typ) {
+				next, ok := scope(s)[name]
+				if !ok {
+					continue
+				}
+				switch state[next] {
+				case visiting:
+					start := len(path) - 1
+					for path[start] != next {
+						start--
+					}
+					var cycle []string
+					for _, p := range path[start:] {
+						cycle = append(cycle, p.name.initialName)
+					}
+					cycle = append(cycle, next.name.initialName)
+					errs = append(errs, newDiagnostic(
+						RecursiveStruct,
+						member,
+						fmt.Sprintf(
+							"struct %q contains itself: %s",
+							next.name.initialName,
+							strings.Join(cycle, " -> "),
+						),
+					))
+				case 0:
+					visit(next)
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[s] = done
+	}
+	for _, s := range w.Structs {
+		if state[s] == 0 {
+			visit(s)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,395 @@
+package wdlparser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResolveCall(t *testing.T) {
+	inputPath := "testdata/call_namespace.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	if err := result.ResolveImports(); err != nil {
+		t.Fatalf("failed to resolve imports of %q: %v", inputPath, err)
+	}
+
+	callee, err := result.ResolveCall(result.Workflow.Calls[0])
+	if err != nil {
+		t.Fatalf("failed to resolve call: %v", err)
+	}
+	task, ok := callee.(*Task)
+	if !ok {
+		t.Fatalf("call resolved to %T, expect *Task", callee)
+	}
+	if task.name.initialName != "Inner" {
+		t.Errorf(
+			"call resolved to task %q, expect %q",
+			task.name.initialName, "Inner",
+		)
+	}
+}
+
+func TestAllImportedDocuments(t *testing.T) {
+	inputPath := "testdata/import_chain.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	if _, err := result.AllImportedDocuments(); err == nil {
+		t.Errorf("expect an error listing unresolved imports")
+	}
+	if err := result.ResolveImports(); err != nil {
+		t.Fatalf("failed to resolve imports of %q: %v", inputPath, err)
+	}
+
+	docs, err := result.AllImportedDocuments()
+	if err != nil {
+		t.Fatalf("failed to list imported documents: %v", err)
+	}
+	var paths []string
+	for _, doc := range docs {
+		paths = append(paths, doc.Path)
+	}
+	expectedPaths := []string{
+		"testdata/lib/chain3.wdl",
+		"testdata/lib/chain2.wdl",
+		"testdata/lib/chain1.wdl",
+	}
+	if diff := cmp.Diff(expectedPaths, paths); diff != "" {
+		t.Errorf("unexpected imported documents:\n%s", diff)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	inputPath := "testdata/call_namespace.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	if err := result.ResolveImports(); err != nil {
+		t.Fatalf("failed to resolve imports of %q: %v", inputPath, err)
+	}
+
+	n, doc, err := result.Resolve("ns.sub.Inner", result.Workflow)
+	if err != nil {
+		t.Fatalf("failed to resolve %q: %v", "ns.sub.Inner", err)
+	}
+	if task, ok := n.(*Task); !ok || task.name.initialName != "Inner" {
+		t.Errorf("resolved to %T, expect task Inner", n)
+	}
+	if doc.Path != "testdata/lib/inner.wdl" {
+		t.Errorf(
+			"resolved in %q, expect %q", doc.Path, "testdata/lib/inner.wdl",
+		)
+	}
+	if _, _, err := result.Resolve("ns.Inner", result.Workflow); err == nil {
+		t.Errorf("expect an error resolving %q", "ns.Inner")
+	}
+
+	inputPath = "testdata/workflow_scatter_gather.wdl"
+	result, errs = Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	from := result.Workflow.Outputs[0]
+	n, doc, err = result.Resolve("Greet.greeting", from)
+	if err != nil {
+		t.Fatalf("failed to resolve %q: %v", "Greet.greeting", err)
+	}
+	if n != result.Tasks[0].Outputs[0] || doc != result {
+		t.Errorf(
+			"unexpected resolution of %q: %T in %q",
+			"Greet.greeting", n, doc.Path,
+		)
+	}
+	n, _, err = result.Resolve("names", from)
+	if err != nil || n != result.Workflow.Inputs[0] {
+		t.Errorf("unexpected resolution of %q: %T, %v", "names", n, err)
+	}
+}
+
+func TestCheckStructCycles(t *testing.T) {
+	inputPath := "testdata/struct_cycle.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	var msgs []string
+	for _, err := range result.CheckStructCycles() {
+		msgs = append(msgs, err.(Diagnostic).Msg)
+	}
+	expected := []string{
+		`struct "Sample" contains itself: Sample -> Run -> Sample`,
+	}
+	if diff := cmp.Diff(expected, msgs); diff != "" {
+		t.Errorf("unexpected struct cycles:\n%s", diff)
+	}
+}
+
+// Structs importing each other under aliases are found in a cycle by their
+// aliases, and named in it as they are defined.
+func TestCheckStructCyclesThroughAliases(t *testing.T) {
+	inputPath := "testdata/struct_cycle_alias.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	if err := result.ResolveImports(); err != nil {
+		t.Fatalf("failed to resolve imports of %q: %v", inputPath, err)
+	}
+	var msgs []string
+	for _, err := range result.CheckStructCycles() {
+		msgs = append(msgs, err.(Diagnostic).Msg)
+	}
+	expected := []string{
+		`struct "Run" contains itself: Run -> Sample -> Run`,
+	}
+	if diff := cmp.Diff(expected, msgs); diff != "" {
+		t.Errorf("unexpected struct cycles:\n%s", diff)
+	}
+}
+
+func TestQualifiedOutputs(t *testing.T) {
+	inputPath := "testdata/workflow_scatter_gather.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	samePointer := cmp.Comparer(func(a, b *valueSpec) bool { return a == b })
+	wf := result.Workflow
+	expected := map[string]*valueSpec{
+		"Gather.greetings": wf.Outputs[0],
+		"Gather.greeted":   wf.Outputs[1],
+	}
+	if diff := cmp.Diff(
+		expected, wf.QualifiedOutputs(), samePointer,
+	); diff != "" {
+		t.Errorf("unexpected qualified outputs:\n%s", diff)
+	}
+
+	expected = map[string]*valueSpec{
+		"Gather.Greet.greeting": result.Tasks[0].Outputs[0],
+	}
+	if diff := cmp.Diff(
+		expected, wf.QualifiedCallOutputs(), samePointer,
+	); diff != "" {
+		t.Errorf("unexpected qualified call outputs:\n%s", diff)
+	}
+}
+
+// A declaration shadows a struct of the same name when a name is resolved as
+// a value, while the struct is still found where there is no such declaration.
+func TestResolveInputShadowsStruct(t *testing.T) {
+	inputPath := "testdata/workflow_input_shadows_struct.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	if err := result.ResolveImports(); err != nil {
+		t.Fatalf("failed to resolve imports of %q: %v", inputPath, err)
+	}
+
+	n, _, err := result.Resolve("Chain", result.Workflow.Outputs[0])
+	if err != nil || n != result.Workflow.Inputs[0] {
+		t.Errorf("resolved %q to %T, %v, expect the input", "Chain", n, err)
+	}
+
+	n, doc, err := result.Resolve("Chain", result)
+	if err != nil {
+		t.Fatalf("failed to resolve %q: %v", "Chain", err)
+	}
+	if s, ok := n.(*Struct); !ok || s.name.initialName != "Chain" {
+		t.Errorf("resolved %q to %T, expect struct Chain", "Chain", n)
+	}
+	if doc.Path != "testdata/lib/chain3.wdl" {
+		t.Errorf(
+			"resolved in %q, expect %q", doc.Path, "testdata/lib/chain3.wdl",
+		)
+	}
+}
+
+func TestResolveStruct(t *testing.T) {
+	inputPath := "testdata/struct_typed_declaration.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	if err := result.ResolveImports(); err != nil {
+		t.Fatalf("failed to resolve imports of %q: %v", inputPath, err)
+	}
+
+	inputs := result.Workflow.Inputs
+	s, err := result.ResolveStruct(inputs[0])
+	if err != nil || s != result.Structs[0] {
+		t.Errorf("resolved %q to %v, %v, expect struct Local", "local", s, err)
+	}
+	s, err = result.ResolveStruct(inputs[1])
+	if err != nil {
+		t.Fatalf("failed to resolve struct of %q: %v", "link", err)
+	}
+	if s.name.initialName != "Chain" {
+		t.Errorf("resolved %q to %q, expect Chain", "link", s.name.initialName)
+	}
+	if _, ok := s.Member("link"); !ok {
+		t.Errorf("struct Chain should have member %q", "link")
+	}
+	if _, ok := s.Member("missing"); ok {
+		t.Errorf("struct Chain should have no member %q", "missing")
+	}
+	_, err = result.ResolveStruct(inputs[2])
+	expectedErr := `type "Int" of "n" is not a known struct`
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("error should be %q is %v", expectedErr, err)
+	}
+}
+
+func TestCalledTasks(t *testing.T) {
+	inputPath := "testdata/workflow_called_tasks.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	expectedTargets := []string{"Local", "qc.Inner", "sub.Sub"}
+	if diff := cmp.Diff(
+		expectedTargets, result.Workflow.CalledTasks(),
+	); diff != "" {
+		t.Errorf("unexpected called tasks:\n%s", diff)
+	}
+
+	if _, err := result.Workflow.ResolveCalledTasks(); err == nil {
+		t.Errorf("expect an error resolving calls before imports")
+	}
+	if err := result.ResolveImports(); err != nil {
+		t.Fatalf("failed to resolve imports of %q: %v", inputPath, err)
+	}
+	tasks, err := result.Workflow.ResolveCalledTasks()
+	if err != nil {
+		t.Fatalf("failed to resolve called tasks: %v", err)
+	}
+	var names []string
+	for _, task := range tasks {
+		names = append(names, documentOf(task).Path+":"+task.name.initialName)
+	}
+	expected := []string{
+		"testdata/workflow_called_tasks.wdl:Local",
+		"testdata/lib/inner.wdl:Inner",
+		"testdata/lib/sub_workflow.wdl:Helper",
+	}
+	if diff := cmp.Diff(expected, names); diff != "" {
+		t.Errorf("unexpected resolved called tasks:\n%s", diff)
+	}
+}
+
+func TestIsConstant(t *testing.T) {
+	inputPath := "testdata/runtime_expressions.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	task := result.Tasks[0]
+	for _, rt := range task.Runtime {
+		for _, ref := range rt.value.References() {
+			decl, _, err := result.Resolve(ref.initialName, rt)
+			if err != nil {
+				t.Errorf(
+					"failed to resolve %q of runtime %q: %v",
+					ref.initialName, rt.name.initialName, err,
+				)
+			} else if _, ok := decl.(*valueSpec); !ok {
+				t.Errorf(
+					"%q of runtime %q resolved to %T, expect *valueSpec",
+					ref.initialName, rt.name.initialName, decl,
+				)
+			}
+		}
+	}
+
+	constant := map[string]bool{}
+	for _, rt := range task.Runtime {
+		constant[rt.name.initialName] = rt.IsConstant()
+	}
+	expected := map[string]bool{
+		"memory":      false,
+		"cpu":         true,
+		"disks":       false,
+		"container":   false,
+		"maxRetries":  true,
+		"preemptible": true,
+	}
+	if diff := cmp.Diff(expected, constant); diff != "" {
+		t.Errorf("unexpected constant runtime attributes:\n%s", diff)
+	}
+	if task.Inputs[1].IsConstant() {
+		t.Errorf("input %q should not be constant", "mem_gb")
+	}
+}
+
+func TestResolveScatterVariable(t *testing.T) {
+	inputPath := "testdata/workflow_scatter.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	scatter := result.Workflow.body()[0].(*Scatter)
+	name := scatter.Body[0].(*valueSpec)
+	n, doc, err := result.Resolve("f", name)
+	if err != nil || n != scatter || doc != result {
+		t.Errorf("resolved %q to %T, %v, expect the scatter", "f", n, err)
+	}
+	if name.IsConstant() {
+		t.Errorf("declaration %q should not be constant", "name")
+	}
+	if _, _, err := result.Resolve("f", result.Workflow); err == nil {
+		t.Errorf("expect an error resolving %q out of the scatter", "f")
+	}
+}
+
+func TestCallWithoutTarget(t *testing.T) {
+	input := "version 1.1 workflow W { call }"
+	result, errs := Antlr4Parse(input)
+	if len(errs) != 1 {
+		t.Errorf("Found %d errors in %q, expect 1 error", len(errs), input)
+	}
+	if len(result.Workflow.Calls) != 1 {
+		t.Fatalf("call of %q should be kept", input)
+	}
+	c := result.Workflow.Calls[0]
+	if len(c.Target) != 0 || c.callName() != "" {
+		t.Errorf("call should have no target is %q", c.Target)
+	}
+	if _, err := result.ResolveCall(c); err == nil {
+		t.Errorf("expect an error resolving a call without target")
+	}
+	if targets := result.Workflow.CalledTasks(); len(targets) != 0 {
+		t.Errorf("unexpected called tasks: %q", targets)
+	}
+	Lint(result)
+}
@@ -0,0 +1,269 @@
+package wdlparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// AllRuntimeKeys returns every key used in runtime sections of a document's
+// tasks along with the number of tasks using it.
+func (w *WDL) AllRuntimeKeys() map[string]int {
+	keys := map[string]int{}
+	for _, t := range w.Tasks {
+		seen := map[string]bool{}
+		for _, kv := range t.Runtime {
+			if !seen[kv.name.initialName] {
+				seen[kv.name.initialName] = true
+				keys[kv.name.initialName]++
+			}
+		}
+	}
+	return keys
+}
+
+// A ContainerImage is an image a task runs in, as given by the container or
+// docker runtime attribute of the task.
+type ContainerImage struct {
+	Task     string
+	Image    string // image name, or source text if it's not a constant
+	Constant bool   // whether the image is a constant string
+}
+
+// ContainerImages returns images given by container or docker runtime
+// attributes of every task of a document, in source order. Each image of an
+// array of alternative images is returned on its own. An attribute computed
+// from declarations is returned in its source text, marked not constant.
+func (w *WDL) ContainerImages() []ContainerImage {
+	var images []ContainerImage
+	for _, t := range w.Tasks {
+		for _, kv := range t.Runtime {
+			switch kv.name.initialName {
+			case "container", "docker":
+			default:
+				continue
+			}
+			name := t.name.initialName
+			constants, ok := kv.value.constantStrings()
+			if !ok {
+				images = append(images, ContainerImage{name, kv.raw, false})
+				continue
+			}
+			for _, image := range constants {
+				images = append(images, ContainerImage{name, image, true})
+			}
+		}
+	}
+	return images
+}
+
+// constantStrings returns the strings a constant string or array of strings
+// evaluates to. It reports false for any other expression.
+func (e *exprRPN) constantStrings() ([]string, bool) {
+	v, ok := e.constant()
+	if !ok {
+		return nil, false
+	}
+	if s, ok := v.govalue.(string); ok {
+		return []string{s}, true
+	}
+	elems, ok := v.govalue.([]value)
+	if !ok {
+		return nil, false
+	}
+	strs := make([]string, 0, len(elems))
+	for _, elem := range elems {
+		s, ok := elem.govalue.(string)
+		if !ok {
+			return nil, false
+		}
+		strs = append(strs, s)
+	}
+	return strs, true
+}
+
+// A DiskSpec is a disk a task asks for with the disks runtime attribute.
+type DiskSpec struct {
+	Mount    string  // mount point, or local-disk, or empty if not given
+	SizeGB   float64 // size in GB, converted from the unit if given
+	DiskType string  // like SSD or HDD, or empty if not given
+}
+
+// diskUnits are sizes of units a disk size may be given in, in GB.
+var diskUnits = map[string]float64{
+	"B":   1e-9,
+	"KB":  1e-6,
+	"MB":  1e-3,
+	"GB":  1,
+	"TB":  1e3,
+	"KiB": float64(1<<10) / 1e9,
+	"MiB": float64(1<<20) / 1e9,
+	"GiB": float64(1<<30) / 1e9,
+	"TiB": float64(1<<40) / 1e9,
+}
+
+// diskTypes are types a disk may be asked for.
+var diskTypes = map[string]bool{"SSD": true, "HDD": true, "LOCAL": true}
+
+// parseDiskSpec parses a disk written like 100, 100 GiB, /mnt/data 50 GB or
+// local-disk 100 SSD, where a size without unit is in GB.
+func parseDiskSpec(s string) (DiskSpec, error) {
+	var disk DiskSpec
+	fields := strings.Fields(s)
+	if len(fields) > 0 {
+		if _, err := strconv.ParseFloat(fields[0], 64); err != nil {
+			disk.Mount, fields = fields[0], fields[1:]
+		}
+	}
+	if len(fields) == 0 || len(fields) > 3 {
+		return disk, fmt.Errorf("disk %q is not a mount, size and type", s)
+	}
+	size, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || size < 0 {
+		return disk, fmt.Errorf("size of disk %q is not a number", s)
+	}
+	disk.SizeGB = size
+	hasUnit := false
+	for _, field := range fields[1:] {
+		if unit, ok := diskUnits[field]; ok && !hasUnit && disk.DiskType == "" {
+			disk.SizeGB, hasUnit = size*unit, true
+		} else if diskTypes[field] && disk.DiskType == "" {
+			disk.DiskType = field
+		} else {
+			return disk, fmt.Errorf(
+				"%q of disk %q is neither a unit nor a disk type", field, s,
+			)
+		}
+	}
+	return disk, nil
+}
+
+// Disks returns disks a task asks for with the disks runtime attribute, which
+// is a size in GB, or a string or an array of strings each holding one or more
+// comma separated disks, like "local-disk 100 SSD, /mnt/data 50 GiB". It
+// returns nil for a task without disks, and an error if disks isn't a
+// constant or can't be parsed.
+func (t *Task) Disks() ([]DiskSpec, error) {
+	for _, kv := range t.Runtime {
+		if kv.name.initialName != "disks" {
+			continue
+		}
+		v, ok := kv.value.constant()
+		if !ok {
+			return nil, fmt.Errorf(
+				"disks of task %q is not a constant", t.name.initialName,
+			)
+		}
+		var specs []string
+		switch g := v.govalue.(type) {
+		case int64:
+			return []DiskSpec{{SizeGB: float64(g)}}, nil
+		case string:
+			specs = []string{g}
+		case []value:
+			for _, elem := range g {
+				s, ok := elem.govalue.(string)
+				if !ok {
+					return nil, fmt.Errorf(
+						"disks of task %q is not an array of strings",
+						t.name.initialName,
+					)
+				}
+				specs = append(specs, s)
+			}
+		default:
+			return nil, fmt.Errorf(
+				"disks of task %q is not a size or string", t.name.initialName,
+			)
+		}
+		var disks []DiskSpec
+		for _, spec := range specs {
+			for _, s := range strings.Split(spec, ",") {
+				disk, err := parseDiskSpec(s)
+				if err != nil {
+					return nil, err
+				}
+				disks = append(disks, disk)
+			}
+		}
+		return disks, nil
+	}
+	return nil, nil
+}
+
+// knownHints are keys of hints with typed accessors on tasks, along with their
+// spellings in earlier WDL versions.
+var knownHints = map[string][]string{
+	"maxRetries":           {"maxRetries", "max_retries"},
+	"localizationOptional": {"localizationOptional", "localization_optional"},
+}
+
+// runtimeAttributes are runtime keys WDL 1.1 reserves for attributes, which
+// engines must honor, as opposed to hints they may ignore.
+var runtimeAttributes = map[string]bool{
+	"container":   true,
+	"docker":      true,
+	"cpu":         true,
+	"memory":      true,
+	"gpu":         true,
+	"disks":       true,
+	"maxRetries":  true,
+	"returnCodes": true,
+}
+
+// hint returns the constant value of a hint, which is looked up by any of its
+// spellings in runtime, where WDL 1.1 puts engine hints like maxRetries. It
+// reports false if the task has no such hint or its value isn't a constant.
+func (t *Task) hint(key string) (value, bool) {
+	for _, kv := range t.Runtime {
+		for _, name := range knownHints[key] {
+			if kv.name.initialName == name {
+				return kv.value.constant()
+			}
+		}
+	}
+	return value{}, false
+}
+
+// MaxRetries returns how many times a task may be retried once it fails. It
+// reports false if the task doesn't say or the number isn't a constant Int.
+func (t *Task) MaxRetries() (int, bool) {
+	v, ok := t.hint("maxRetries")
+	if !ok {
+		return 0, false
+	}
+	n, ok := v.govalue.(int64)
+	return int(n), ok
+}
+
+// LocalizationOptional returns whether File inputs of a task may be left
+// where they are instead of being localized. It reports false if the task
+// doesn't say or the flag isn't a constant Boolean.
+func (t *Task) LocalizationOptional() (bool, bool) {
+	v, ok := t.hint("localizationOptional")
+	if !ok {
+		return false, false
+	}
+	b, ok := v.govalue.(bool)
+	return b, ok
+}
+
+// UnknownHints returns source text of values of runtime keys which are
+// neither reserved attributes nor hints with typed accessors, keyed by their
+// names.
+func (t *Task) UnknownHints() map[string]string {
+	known := map[string]bool{}
+	for _, names := range knownHints {
+		for _, name := range names {
+			known[name] = true
+		}
+	}
+	hints := map[string]string{}
+	for _, kv := range t.Runtime {
+		name := kv.name.initialName
+		if !known[name] && !runtimeAttributes[name] {
+			hints[name] = kv.raw
+		}
+	}
+	return hints
+}
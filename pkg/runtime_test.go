@@ -0,0 +1,152 @@
+package wdlparser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAllRuntimeKeys(t *testing.T) {
+	inputPath := "testdata/runtime_container.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	expected := map[string]int{"container": 2, "docker": 1, "cpu": 1}
+	if diff := cmp.Diff(expected, result.AllRuntimeKeys()); diff != "" {
+		t.Errorf("unexpected runtime keys:\n%s", diff)
+	}
+}
+
+func TestContainerImages(t *testing.T) {
+	testCases := []struct {
+		inputPath string
+		want      []ContainerImage
+	}{
+		{
+			"testdata/runtime_container.wdl",
+			[]ContainerImage{
+				{"Align", "biocontainers/bwa:latest", true},
+				{"Sort", "biocontainers/samtools:latest", true},
+				{"Index", "biocontainers/samtools:latest", true},
+			},
+		},
+		{
+			"testdata/runtime_container_images.wdl",
+			[]ContainerImage{
+				{"Align", `"biocontainers/bwa:~{tag}"`, false},
+				{"Sort", "quay.io/samtools:1.9", true},
+				{"Sort", "biocontainers/samtools:1.9", true},
+				{
+					"Index",
+					`"biocontainers/samtools:~{default='1.9' tag}"`,
+					false,
+				},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		result, err := Antlr4Parse(tc.inputPath)
+		if err != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors",
+				len(err), tc.inputPath,
+			)
+		}
+		if diff := cmp.Diff(tc.want, result.ContainerImages()); diff != "" {
+			t.Errorf(
+				"unexpected container images of %q:\n%s", tc.inputPath, diff,
+			)
+		}
+	}
+}
+
+func TestDisks(t *testing.T) {
+	testCases := []struct {
+		disks string
+		want  []DiskSpec
+		err   bool
+	}{
+		{"100", []DiskSpec{{SizeGB: 100}}, false},
+		{`"local-disk 100 SSD"`, []DiskSpec{{"local-disk", 100, "SSD"}}, false},
+		{
+			`"local-disk 10 HDD, /mnt/data 2 TB"`,
+			[]DiskSpec{{"local-disk", 10, "HDD"}, {"/mnt/data", 2000, ""}},
+			false,
+		},
+		{
+			`["/mnt/a 1 GiB", "/mnt/b 5"]`,
+			[]DiskSpec{{"/mnt/a", 1.073741824, ""}, {"/mnt/b", 5, ""}},
+			false,
+		},
+		{`"local-disk SSD"`, nil, true},
+		{`"local-disk 10 GB GB"`, nil, true},
+		{"size", nil, true},
+		{`"~{default='local-disk 10 SSD' d}"`, nil, true},
+	}
+	for _, tc := range testCases {
+		wdl := "version 1.1 task T { command <<< >>> runtime { disks: " +
+			tc.disks + " } }"
+		result, err := Antlr4Parse(wdl)
+		if err != nil {
+			t.Errorf("Found %d errors in %q, expect no errors", len(err), wdl)
+			continue
+		}
+		disks, e := result.Tasks[0].Disks()
+		if (e != nil) != tc.err {
+			t.Errorf("unexpected error parsing disks %s: %v", tc.disks, e)
+		}
+		if diff := cmp.Diff(tc.want, disks); diff != "" {
+			t.Errorf("unexpected disks of %s:\n%s", tc.disks, diff)
+		}
+	}
+}
+
+func TestHints(t *testing.T) {
+	type hints struct {
+		MaxRetries           int
+		HasMaxRetries        bool
+		LocalizationOptional bool
+		HasLocalization      bool
+		Unknown              map[string]string
+	}
+	testCases := []struct {
+		runtime string
+		want    hints
+	}{
+		{"cpu: 1", hints{Unknown: map[string]string{}}},
+		{
+			"maxRetries: 1 + 2 preemptible: 1",
+			hints{3, true, false, false, map[string]string{"preemptible": "1"}},
+		},
+		{
+			"max_retries: n localization_optional: true",
+			hints{0, false, true, true, map[string]string{}},
+		},
+		{
+			`maxRetries: 2 localizationOptional: "yes" inputs: 1 + 1`,
+			hints{2, true, false, false, map[string]string{"inputs": "1 + 1"}},
+		},
+	}
+	for _, tc := range testCases {
+		wdl := "version 1.1 task T { command <<< >>> runtime { " +
+			tc.runtime + " } }"
+		result, err := Antlr4Parse(wdl)
+		if err != nil {
+			t.Errorf("Found %d errors in %q, expect no errors", len(err), wdl)
+			continue
+		}
+		task := result.Tasks[0]
+		var got hints
+		got.MaxRetries, got.HasMaxRetries = task.MaxRetries()
+		got.LocalizationOptional, got.HasLocalization =
+			task.LocalizationOptional()
+		got.Unknown = task.UnknownHints()
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("unexpected hints of %q:\n%s", tc.runtime, diff)
+		}
+	}
+}
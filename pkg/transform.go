@@ -0,0 +1,65 @@
+package wdlparser
+
+// A Transformer rewrites a parsed WDL document, for example to inline
+// constant inputs. It may modify the given document in place and return it, or
+// return a new document.
+type Transformer interface {
+	Transform(*WDL) (*WDL, error)
+}
+
+// The TransformerFunc type is an adapter to allow the use of ordinary
+// functions as transformers.
+type TransformerFunc func(*WDL) (*WDL, error)
+
+// Transform calls f(w).
+func (f TransformerFunc) Transform(w *WDL) (*WDL, error) {
+	return f(w)
+}
+
+// Apply runs transformers in order over a copy of a document, each
+// transforming the result of the previous one, and stops at the first error.
+// The document itself is left unchanged, even if a transformer fails, while
+// imported documents are shared with the copy as Clone does.
+func Apply(w *WDL, ts ...Transformer) (*WDL, error) {
+	w = w.Clone()
+	for _, t := range ts {
+		var err error
+		if w, err = t.Transform(w); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// RenameTasks returns a transformer renaming every task of a document by a
+// function of its name, along with targets of calls to it in the workflow of
+// the document, so that the calls still resolve to it. A call without alias
+// is given the former task name as alias, which keeps references to it, like
+// to its outputs, unchanged.
+func RenameTasks(rename func(string) string) Transformer {
+	return TransformerFunc(func(w *WDL) (*WDL, error) {
+		renamed := map[string]string{}
+		for _, t := range w.Tasks {
+			renamed[t.name.initialName] = rename(t.name.initialName)
+			t.name.initialName = renamed[t.name.initialName]
+		}
+		if w.Workflow == nil {
+			return w, nil
+		}
+		for _, c := range w.Workflow.Calls {
+			if len(c.Target) != 1 {
+				continue // a call to an imported document
+			}
+			name, ok := renamed[c.Target[0]]
+			if !ok {
+				continue
+			}
+			if c.alias == "" {
+				c.alias = c.Target[0]
+			}
+			c.Target = []string{name}
+			c.name.initialName = name
+		}
+		return w, nil
+	})
+}
@@ -0,0 +1,66 @@
+package wdlparser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApply(t *testing.T) {
+	inputPath := "testdata/version1_1.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+
+	prefix := RenameTasks(func(name string) string { return "X_" + name })
+	transformed, err := Apply(result, prefix)
+	if err != nil {
+		t.Fatalf("failed to transform %q: %v", inputPath, err)
+	}
+	if diff := cmp.Diff(
+		"X_WriteGreeting", transformed.Tasks[0].name.initialName,
+	); diff != "" {
+		t.Errorf("unexpected task name:\n%s", diff)
+	}
+	call := transformed.Workflow.Calls[0]
+	if diff := cmp.Diff([]string{"X_WriteGreeting"}, call.Target); diff != "" {
+		t.Errorf("unexpected call target:\n%s", diff)
+	}
+	if diff := cmp.Diff("WriteGreeting", call.callName()); diff != "" {
+		t.Errorf("unexpected call name:\n%s", diff)
+	}
+	callee, err := transformed.ResolveCall(call)
+	if err != nil || callee != transformed.Tasks[0] {
+		t.Errorf("call to renamed task is not resolved: %v", err)
+	}
+	if result.Tasks[0].name.initialName != "WriteGreeting" ||
+		result.Workflow.Calls[0].Target[0] != "WriteGreeting" {
+		t.Errorf("the transformed document should be left unchanged")
+	}
+
+	failure := errors.New("failure")
+	ran := false
+	after := TransformerFunc(func(w *WDL) (*WDL, error) {
+		ran = true
+		return w, nil
+	})
+	_, err = Apply(
+		result,
+		prefix,
+		TransformerFunc(func(*WDL) (*WDL, error) { return nil, failure }),
+		after,
+	)
+	if err != failure {
+		t.Errorf("unexpected error %v, expect %v", err, failure)
+	}
+	if ran {
+		t.Errorf("transformer after a failure should not run")
+	}
+	if result.Tasks[0].name.initialName != "WriteGreeting" {
+		t.Errorf("a failed transformation should leave the document unchanged")
+	}
+}
@@ -0,0 +1,472 @@
+package wdlparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rule names of type checking diagnostics
+const (
+	MixedTypeLiteral = "MixedTypeLiteral" // elements of different types
+	TypeMismatch     = "TypeMismatch"     // value not of the declared type
+)
+
+// parseType converts a raw WDL type, like Array[Int]+?, into a Type. It returns
+// nil for types it doesn't model, like structs and pairs.
+func parseType(rawType string) Type {
+	if strings.HasSuffix(rawType, "?") {
+		base := parseType(strings.TrimSuffix(rawType, "?"))
+		if base == nil {
+			return nil
+		}
+		return OptionalOf(base)
+	}
+	rawType = strings.TrimSuffix(rawType, "+")
+	switch {
+	case strings.HasPrefix(rawType, "Array[") &&
+		strings.HasSuffix(rawType, "]"):
+		elem := parseType(rawType[len("Array[") : len(rawType)-1])
+		if elem == nil {
+			return nil
+		}
+		return ArrayOf(elem)
+	case strings.HasPrefix(rawType, "Map[") && strings.HasSuffix(rawType, "]"):
+		inner := rawType[len("Map[") : len(rawType)-1]
+		depth := 0
+		for i, r := range inner {
+			switch r {
+			case '[':
+				depth++
+			case ']':
+				depth--
+			case ',':
+				if depth > 0 {
+					continue
+				}
+				key, value := parseType(inner[:i]), parseType(inner[i+1:])
+				if key == nil || value == nil {
+					return nil
+				}
+				return MapOf(key, value)
+			}
+		}
+		return nil
+	}
+	switch p := primitive(strings.TrimSpace(rawType)); p {
+	case Boolean, Int, Float, String, File, Directory:
+		return p
+	}
+	return nil
+}
+
+// unify returns the type shared by all given types, where Int and Float unify
+// to Float, and None or an optional type, like Int? with Int, makes the shared
+// type optional. It returns nil if any type is unknown, and reports false if
+// types differ otherwise.
+func unify(types []Type) (Type, bool) {
+	var unified Type
+	for _, t := range types {
+		switch {
+		case t == nil:
+			return nil, true
+		case unified == nil, unified == t:
+			unified = t
+		case unified == None, t == OptionalOf(unified):
+			unified = OptionalOf(t)
+		case t == None, unified == OptionalOf(t):
+			unified = OptionalOf(unified)
+		case (unified == Int || unified == Float) && (t == Int || t == Float):
+			unified = Float
+		default:
+			return nil, false
+		}
+	}
+	return unified, true
+}
+
+// returnTypes are types of values returned by functions of the standard
+// library whose return types don't depend on their arguments, like
+// glob(String) -> Array[File].
+var returnTypes = map[string]Type{
+	"stdout":       File,
+	"stderr":       File,
+	"glob":         ArrayOf(File),
+	"read_string":  String,
+	"read_int":     Int,
+	"read_float":   Float,
+	"read_boolean": Boolean,
+	"read_lines":   ArrayOf(String),
+	"read_tsv":     ArrayOf(ArrayOf(String)),
+	"read_map":     MapOf(String, String),
+	"write_lines":  File,
+	"write_tsv":    File,
+	"write_map":    File,
+	"write_json":   File,
+	"basename":     String,
+	"sub":          String,
+	"sep":          String,
+	"size":         Float,
+	"length":       Int,
+	"floor":        Int,
+	"ceil":         Int,
+	"round":        Int,
+	"range":        ArrayOf(Int),
+	"defined":      Boolean,
+}
+
+// inferType infers the type of the value an RPN evaluates to, where names it
+// refers to, like x or call.output, are typed by scope. It returns nil if the
+// type can't be known without evaluation. An empty array or map literal takes
+// the type of context, which is the declared type if any, provided that it's
+// an array or map. An array or map literal with elements of different types
+// has elements typed as Any, or is an error under strict.
+func (e exprRPN) inferType(
+	context Type, scope map[string]Type, strict bool,
+) (Type, error) {
+	if o, ok := context.(optional); ok {
+		context = o.base
+	}
+	var stack []Type
+	var names []string // names referred to by operands in stack, if any
+	pop := func(n int) []Type {
+		if len(stack) < n {
+			// Operands the parser doesn't model yet, like function calls
+			stack, names = nil, nil
+			return make([]Type, n)
+		}
+		operands := stack[len(stack)-n:]
+		stack, names = stack[:len(stack)-n], names[:len(names)-n]
+		return operands
+	}
+	for _, elem := range e {
+		name := ""
+		switch elem := elem.(type) {
+		case value:
+			stack = append(stack, elem.typ)
+		case *identifier:
+			name = elem.initialName
+			stack = append(stack, scope[name])
+		case member:
+			if len(names) > 0 && names[len(names)-1] != "" {
+				name = names[len(names)-1] + "." + string(elem)
+			}
+			pop(1)
+			stack = append(stack, scope[name])
+		case *expression:
+			t, err := elem.rpn.inferType(nil, scope, strict)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, t)
+		case WDLOpSym:
+			var t Type
+			switch elem {
+			case WDLStr:
+				pop(1)
+				t = String
+			case WDLNot:
+				pop(1)
+				t = Boolean
+			case WDLNeg:
+				t = pop(1)[0]
+			case WDLTernary:
+				t, _ = unify(pop(3)[1:])
+			case WDLEq, WDLNeq, WDLLt, WDLLte, WDLGt, WDLGte, WDLAnd, WDLOr:
+				pop(2)
+				t = Boolean
+			case WDLAdd:
+				operands := pop(2)
+				if operands[0] == String || operands[1] == String {
+					t = String
+					break
+				}
+				t, _ = unify(operands)
+			default:
+				t, _ = unify(pop(2))
+			}
+			stack = append(stack, t)
+		case function:
+			stack = append(stack, functionType(elem.name, pop(elem.n)))
+		case nAryOp:
+			operands := pop(elem.n)
+			t, err := literalType(elem.op, operands, context, strict)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, t)
+		default:
+			stack = append(stack, nil)
+		}
+		names = append(names, name)
+	}
+	if len(stack) != 1 {
+		return nil, nil
+	}
+	return stack[0], nil
+}
+
+// functionType infers the type of the value a function of the standard library
+// returns given types of its arguments. Functions handling optional values
+// return non-optional values: select_first returns the type of elements of its
+// array argument, and select_all returns an array of them.
+func functionType(name string, args []Type) Type {
+	switch name {
+	case "select_first", "select_all":
+		if len(args) != 1 {
+			return nil
+		}
+		a, ok := args[0].(array)
+		if !ok {
+			return nil
+		}
+		elem := a.elem
+		if o, ok := elem.(optional); ok {
+			elem = o.base
+		}
+		if name == "select_all" {
+			return ArrayOf(elem)
+		}
+		return elem
+	}
+	return returnTypes[name]
+}
+
+// literalType infers the type of an array or map literal from the types of its
+// elements.
+func literalType(
+	op WDLOpSym, elems []Type, context Type, strict bool,
+) (Type, error) {
+	switch op {
+	case WDLArray:
+		if len(elems) == 0 {
+			if a, ok := context.(array); ok {
+				return a, nil
+			}
+			return ArrayOf(Any), nil
+		}
+		elem, ok := unify(elems)
+		switch {
+		case !ok && strict:
+			return nil, fmt.Errorf(
+				"array literal has elements of different types",
+			)
+		case !ok:
+			return ArrayOf(Any), nil
+		case elem == nil:
+			return nil, nil
+		}
+		return ArrayOf(elem), nil
+	case WDLMap:
+		if len(elems) == 0 {
+			if m, ok := context.(mapping); ok {
+				return m, nil
+			}
+			return MapOf(Any, Any), nil
+		}
+		var keys, values []Type
+		for i := 0; i < len(elems); i += 2 {
+			keys = append(keys, elems[i])
+			values = append(values, elems[i+1])
+		}
+		key, keyOK := unify(keys)
+		val, valOK := unify(values)
+		if (!keyOK || !valOK) && strict {
+			return nil, fmt.Errorf(
+				"map literal has keys or values of different types",
+			)
+		}
+		if !keyOK {
+			key = Any
+		}
+		if !valOK {
+			val = Any
+		}
+		if key == nil || val == nil {
+			return nil, nil
+		}
+		return MapOf(key, val), nil
+	}
+	return nil, nil
+}
+
+// enclosing returns the workflow or task a node is in, or nil if there isn't
+// one.
+func enclosing(n node) node {
+	for ; n != nil; n = n.getParent() {
+		switch n.(type) {
+		case *Workflow, *Task:
+			return n
+		}
+	}
+	return nil
+}
+
+// gathered returns the type of a name declared in scatters or conditionals, as
+// seen from outside of those blocks which don't enclose a given node. A
+// scatter gathers values into an array and a conditional makes them optional.
+func gathered(t Type, declared, from node) Type {
+	ancestors := map[node]bool{}
+	for n := from; n != nil; n = n.getParent() {
+		ancestors[n] = true
+	}
+	for n := declared.getParent(); t != nil && !ancestors[n]; n = n.getParent() {
+		switch n.(type) {
+		case *Scatter:
+			t = ArrayOf(t)
+		case *Conditional:
+			t = OptionalOf(t)
+		default:
+			return t
+		}
+	}
+	return t
+}
+
+// scope returns types of names visible from a node in a workflow or task.
+// Outputs of a call are named like call.output, where the call is named by
+// its alias or callee.
+func (w *WDL) scope(from node) map[string]Type {
+	scope := map[string]Type{}
+	switch container := enclosing(from).(type) {
+	case *Task:
+		decls := append(append(
+			append([]*valueSpec(nil), container.Inputs...),
+			container.PrvtDecls...),
+			container.Outputs...,
+		)
+		for _, decl := range decls {
+			scope[decl.name.initialName] = parseType(decl.typ)
+		}
+	case *Workflow:
+		for _, decl := range container.Inputs {
+			scope[decl.name.initialName] = parseType(decl.typ)
+		}
+		for _, decl := range container.PrvtDecls {
+			scope[decl.name.initialName] = gathered(
+				parseType(decl.typ), decl, from,
+			)
+		}
+		for _, c := range container.Calls {
+			callee, err := w.ResolveCall(c)
+			if err != nil {
+				continue
+			}
+			var outputs []*valueSpec
+			switch callee := callee.(type) {
+			case *Task:
+				outputs = callee.Outputs
+			case *Workflow:
+				outputs = callee.Outputs
+			}
+			name := c.callName()
+			for _, output := range outputs {
+				scope[name+"."+output.name.initialName] = gathered(
+					parseType(output.typ), c, from,
+				)
+			}
+		}
+		for n := from.getParent(); n != nil && n != container; n = n.getParent() {
+			if s, ok := n.(*Scatter); ok {
+				collection, _ := s.Collection.inferType(
+					nil, w.scope(s), false,
+				)
+				if a, ok := collection.(array); ok {
+					scope[s.Variable] = a.elem
+				}
+			}
+		}
+	}
+	return scope
+}
+
+// InferType infers the type of the value bound to a declaration, where names
+// it refers to are typed as seen from the declaration. For example, an output
+// of a call in a scatter is an array outside of the scatter. It returns nil if
+// the type can't be inferred.
+func (w *WDL) InferType(decl *valueSpec) Type {
+	t, _ := decl.value.inferType(parseType(decl.typ), w.scope(decl), false)
+	return t
+}
+
+// coercible reports whether a value of a type can be bound to a declaration of
+// another type. Besides values of the same type, WDL coerces Int to Float, and
+// String to File or Directory and back, including as elements of arrays and
+// maps. A value of unknown type is assumed to be coercible.
+func coercible(from, to Type) bool {
+	if from == nil || from == to || from == Any {
+		return true
+	}
+	if o, ok := to.(optional); ok {
+		to = o.base
+		if o, ok := from.(optional); ok {
+			from = o.base
+		}
+		return coercible(from, to)
+	}
+	switch from := from.(type) {
+	case primitive:
+		switch {
+		case from == Int && to == Float,
+			from == String && (to == File || to == Directory),
+			(from == File || from == Directory) && to == String:
+			return true
+		}
+	case array:
+		if to, ok := to.(array); ok {
+			return coercible(from.elem, to.elem)
+		}
+	case mapping:
+		if to, ok := to.(mapping); ok {
+			return coercible(from.key, to.key) &&
+				coercible(from.value, to.value)
+		}
+	}
+	return false
+}
+
+// CheckType checks the type of the value bound to a declaration against its
+// declared type, where names the value refers to are typed as seen from the
+// declaration. A mismatch is returned as a Diagnostic.
+func (v *valueSpec) CheckType() error {
+	declared := parseType(v.typ)
+	if declared == nil || len(*v.value) == 0 {
+		return nil
+	}
+	var scope map[string]Type
+	if doc := documentOf(v); doc != nil {
+		scope = doc.scope(v)
+	}
+	inferred, err := v.value.inferType(declared, scope, true)
+	if err != nil {
+		return newDiagnostic(
+			MixedTypeLiteral,
+			v,
+			fmt.Sprintf("%q: %v", v.name.initialName, err),
+		)
+	}
+	if !coercible(inferred, declared) {
+		return newDiagnostic(
+			TypeMismatch,
+			v,
+			fmt.Sprintf(
+				"%q is declared as %s but bound to %s",
+				v.name.initialName,
+				declared.typeString(),
+				inferred.typeString(),
+			),
+		)
+	}
+	return nil
+}
+
+// CheckTypes checks types of values bound to all declarations of a document
+// against their declared types.
+func CheckTypes(w *WDL) []Diagnostic {
+	var diags []Diagnostic
+	for _, decl := range w.declarations() {
+		if err := decl.CheckType(); err != nil {
+			diags = append(diags, err.(Diagnostic))
+		}
+	}
+	return diags
+}
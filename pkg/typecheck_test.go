@@ -0,0 +1,253 @@
+package wdlparser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestArrayLiteral(t *testing.T) {
+	result, err := Antlr4Parse(
+		"version 1.1 workflow Test {input{Array[Int] t=[1, 2]}}",
+	)
+	if err != nil {
+		t.Errorf("Found %d errors, expect no errors", len(err))
+	}
+	expected := exprRPN{
+		&expression{
+			genNode: genNode{start: 47, end: 47},
+			rpn:     exprRPN{value{Int, int64(1)}},
+		},
+		&expression{
+			genNode: genNode{start: 50, end: 50},
+			rpn:     exprRPN{value{Int, int64(2)}},
+		},
+		nAryOp{WDLArray, 2},
+	}
+	if diff := cmp.Diff(
+		expected,
+		*result.Workflow.Inputs[0].value,
+		commonCmpopts,
+		cmp.AllowUnexported(nAryOp{}),
+	); diff != "" {
+		t.Errorf("unexpected array literal:\n%s", diff)
+	}
+}
+
+func TestInferLiteralType(t *testing.T) {
+	testCases := []struct {
+		decl   string
+		want   Type
+		strict bool // whether inference fails under strict
+	}{
+		{"Array[Int] t = [1, 2, 3]", ArrayOf(Int), false},
+		{`Array[String] t = ["a", 1]`, ArrayOf(Any), true},
+		{"Array[Float] t = [1, 2.0]", ArrayOf(Float), false},
+		{"Array[Array[Int]] t = [[1], [2, 3]]", ArrayOf(ArrayOf(Int)), false},
+		{"Array[String] t = []", ArrayOf(String), false},
+		{`Map[String, Int] t = {"a": 1, "b": 2}`, MapOf(String, Int), false},
+		{`Map[String, Int] t = {"a": 1, "b": "c"}`, MapOf(String, Any), true},
+		{"Array[Int] t = [1, x]", nil, false},
+		{"Int? t = None", None, false},
+		{"Array[Int?] t = [1, None, 2]", ArrayOf(OptionalOf(Int)), false},
+		{"Array[Int?] t = [None, 1]", ArrayOf(OptionalOf(Int)), false},
+	}
+	for _, tc := range testCases {
+		wdl := "version 1.1 workflow Test {input{" + tc.decl + "}}"
+		result, err := Antlr4Parse(wdl)
+		if err != nil {
+			t.Errorf("Found %d errors in %q, expect no errors", len(err), wdl)
+		}
+		decl := result.Workflow.Inputs[0]
+		typ, e := decl.value.inferType(parseType(decl.typ), nil, false)
+		if e != nil {
+			t.Errorf("failed to infer type of %q: %v", tc.decl, e)
+		}
+		if typ != tc.want {
+			t.Errorf("inferred %v for %q, expect %v", typ, tc.decl, tc.want)
+		}
+		_, e = decl.value.inferType(parseType(decl.typ), nil, true)
+		if (e != nil) != tc.strict {
+			t.Errorf("unexpected error under strict for %q: %v", tc.decl, e)
+		}
+	}
+}
+
+func TestCheckTypes(t *testing.T) {
+	result, err := Antlr4Parse(`version 1.1
+workflow Test {
+    input {
+        Array[Int] ints = [1, 2, 3]
+        Array[String] mixed = ["a", 1]
+    }
+}`)
+	if err != nil {
+		t.Errorf("Found %d errors, expect no errors", len(err))
+	}
+	diags := CheckTypes(result)
+	if len(diags) != 1 || diags[0].Rule != MixedTypeLiteral {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if diags[0].Start != 84 {
+		t.Errorf("diagnostic starts at %d, expect 84", diags[0].Start)
+	}
+}
+
+func TestNoneLiteral(t *testing.T) {
+	result, err := Antlr4Parse(`version 1.1
+workflow Test {
+    input {
+        Int? optional = None
+        Int required = None
+    }
+}`)
+	if err != nil {
+		t.Errorf("Found %d errors, expect no errors", len(err))
+	}
+	none := (*result.Workflow.Inputs[0].value)[0]
+	if diff := cmp.Diff(
+		value{None, nil}, none, cmp.AllowUnexported(value{}, optional{}),
+	); diff != "" {
+		t.Errorf("unexpected None literal:\n%s", diff)
+	}
+	diags := CheckTypes(result)
+	if len(diags) != 1 || diags[0].Rule != TypeMismatch {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if diags[0].Start != 77 {
+		t.Errorf("diagnostic starts at %d, expect 77", diags[0].Start)
+	}
+}
+
+func TestInferScatterGatherType(t *testing.T) {
+	inputPath := "testdata/workflow_scatter_gather.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	testCases := []struct {
+		decl *valueSpec
+		want Type
+	}{
+		{result.Workflow.PrvtDecls[0], String},
+		{result.Workflow.Outputs[0], ArrayOf(File)},
+		{result.Workflow.Outputs[1], ArrayOf(String)},
+	}
+	for _, tc := range testCases {
+		if typ := result.InferType(tc.decl); typ != tc.want {
+			t.Errorf(
+				"inferred %v for %q, expect %v",
+				typ, tc.decl.name.initialName, tc.want,
+			)
+		}
+	}
+}
+
+func TestInferFunctionType(t *testing.T) {
+	inputPath := "testdata/task_output_glob.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	testCases := []struct {
+		decl *valueSpec
+		want Type
+	}{
+		{result.Tasks[0].Outputs[0], ArrayOf(File)},
+		{result.Tasks[0].Outputs[1], Int},
+	}
+	for _, tc := range testCases {
+		if typ := result.InferType(tc.decl); typ != tc.want {
+			t.Errorf(
+				"inferred %v for %q, expect %v",
+				typ, tc.decl.name.initialName, tc.want,
+			)
+		}
+	}
+	if diags := CheckTypes(result); diags != nil {
+		t.Errorf("unexpected diagnostics: %v", diags)
+	}
+}
+
+func TestInferOptionalFunctionType(t *testing.T) {
+	result, err := Antlr4Parse(`version 1.1
+workflow Test {
+    input {
+        Int? a
+        Int? b
+    }
+    Boolean isDefined = defined(a)
+    Int first = select_first([a, b])
+    Int firstOrOne = select_first([a, 1])
+    Array[Int] all = select_all([a, b])
+    Int unwrapped = a
+}`)
+	if err != nil {
+		t.Errorf("Found %d errors, expect no errors", len(err))
+	}
+
+	decls := result.Workflow.PrvtDecls
+	testCases := []struct {
+		decl *valueSpec
+		want Type
+	}{
+		{decls[0], Boolean},
+		{decls[1], Int},
+		{decls[2], Int},
+		{decls[3], ArrayOf(Int)},
+		{decls[4], OptionalOf(Int)},
+	}
+	for _, tc := range testCases {
+		if typ := result.InferType(tc.decl); typ != tc.want {
+			t.Errorf(
+				"inferred %v for %q, expect %v",
+				typ, tc.decl.name.initialName, tc.want,
+			)
+		}
+	}
+	diags := CheckTypes(result)
+	if len(diags) != 1 || diags[0].Start != decls[4].getStart() {
+		t.Errorf("expect a diagnostic on %q only: %v", "unwrapped", diags)
+	}
+}
+
+func TestCheckType(t *testing.T) {
+	testCases := []struct {
+		decl string
+		want string // rule of the expected diagnostic, if any
+	}{
+		{`Int x = "hello"`, TypeMismatch},
+		{"String x = 1", TypeMismatch},
+		{"Int x = 1.5", TypeMismatch},
+		{"Array[Int] x = [1.5]", TypeMismatch},
+		{"Float x = 1", ""},
+		{`File x = "a.txt"`, ""},
+		{`Directory x = "data/"`, ""},
+		{`Array[Directory] x = ["a/", "b/"]`, ""},
+		{"Directory x = 1", TypeMismatch},
+		{"Array[Float] x = [1, 2]", ""},
+		{"Int? x = 1", ""},
+		{"Int x = y", ""},
+		{`Array[String] x = ["a", 1]`, MixedTypeLiteral},
+	}
+	for _, tc := range testCases {
+		wdl := "version 1.1 workflow Test {input{" + tc.decl + "}}"
+		result, err := Antlr4Parse(wdl)
+		if err != nil {
+			t.Errorf("Found %d errors in %q, expect no errors", len(err), wdl)
+		}
+		e := result.Workflow.Inputs[0].CheckType()
+		switch {
+		case tc.want == "" && e != nil:
+			t.Errorf("unexpected error checking %q: %v", tc.decl, e)
+		case tc.want != "" && (e == nil || e.(Diagnostic).Rule != tc.want):
+			t.Errorf("got %v checking %q, expect %s", e, tc.decl, tc.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package wdlparser
+
+// TrulyUnusedInputs returns inputs of a task which nothing evaluated when the
+// task runs refers to: defaults of other inputs, private declarations, command
+// placeholders, outputs and runtime. Metadata and parameter metadata don't
+// count as uses since they only describe inputs. A declaration referring to
+// its own name doesn't count either, but a runtime key named after the input
+// it refers to, like cpu: cpu, does.
+func (t *Task) TrulyUnusedInputs() []*valueSpec {
+	used := map[string]bool{}
+	use := func(self *valueSpec, e *exprRPN) {
+		for _, ref := range e.References() {
+			if self == nil || ref.initialName != self.name.initialName {
+				used[ref.initialName] = true
+			}
+		}
+	}
+	for _, decls := range [][]*valueSpec{t.Inputs, t.PrvtDecls} {
+		for _, decl := range decls {
+			use(decl, decl.value)
+		}
+	}
+	for _, kvs := range [][]*valueSpec{t.Outputs, t.Runtime} {
+		for _, kv := range kvs {
+			use(nil, kv.value)
+		}
+	}
+	for _, part := range t.commandParts {
+		if e, ok := part.(*expression); ok {
+			use(nil, &e.rpn)
+		}
+	}
+	var unused []*valueSpec
+	for _, input := range t.Inputs {
+		if !used[input.name.initialName] {
+			unused = append(unused, input)
+		}
+	}
+	return unused
+}
@@ -0,0 +1,48 @@
+package wdlparser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTrulyUnusedInputs(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  []string
+	}{
+		{"testdata/task_unused_input.wdl", []string{"unused_flag"}},
+		{
+			`version 1.1
+task T {
+    input {
+        Int cpu = 1
+        String docker
+        String? unused
+    }
+    command <<< >>>
+    runtime {
+        cpu: cpu
+        docker: docker
+    }
+}`,
+			[]string{"unused"},
+		},
+	}
+	for _, tc := range testCases {
+		result, errs := Antlr4Parse(tc.input)
+		if errs != nil {
+			t.Errorf(
+				"Found %d errors in %q, expect no errors", len(errs), tc.input,
+			)
+			continue
+		}
+		var names []string
+		for _, input := range result.Tasks[0].TrulyUnusedInputs() {
+			names = append(names, input.name.initialName)
+		}
+		if diff := cmp.Diff(tc.want, names); diff != "" {
+			t.Errorf("unexpected unused inputs of %q:\n%s", tc.input, diff)
+		}
+	}
+}
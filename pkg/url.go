@@ -0,0 +1,88 @@
+package wdlparser
+
+import (
+	"regexp"
+	"sort"
+)
+
+// urlPattern matches URLs of schemes data is commonly fetched with.
+var urlPattern = regexp.MustCompile(`\b(?:https?|gs|s3)://[^\s"'<>]+`)
+
+// stringLiterals returns string literals in an RPN, including those in
+// sub-expressions and placeholder options.
+func (e *exprRPN) stringLiterals() []string {
+	var strs []string
+	for _, elem := range *e {
+		switch elem := elem.(type) {
+		case value:
+			if s, ok := elem.govalue.(string); ok {
+				strs = append(strs, s)
+			}
+		case *expression:
+			strs = append(strs, elem.rpn.stringLiterals()...)
+			names := make([]string, 0, len(elem.options))
+			for name := range elem.options {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if s, ok := elem.options[name].govalue.(string); ok {
+					strs = append(strs, s)
+				}
+			}
+		}
+	}
+	return strs
+}
+
+// metaStrings returns strings in a metadata value.
+func metaStrings(meta interface{}) []string {
+	switch meta := meta.(type) {
+	case string:
+		return []string{meta}
+	case []interface{}:
+		var strs []string
+		for _, v := range meta {
+			strs = append(strs, metaStrings(v)...)
+		}
+		return strs
+	case map[string]interface{}:
+		keys := make([]string, 0, len(meta))
+		for key := range meta {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var strs []string
+		for _, key := range keys {
+			strs = append(strs, metaStrings(meta[key])...)
+		}
+		return strs
+	}
+	return nil
+}
+
+// EmbeddedURLs returns URLs with an http, https, gs or s3 scheme found in
+// string literals of a document, like reference data in input defaults or
+// metadata, in source order without duplicates. URIs of imports are not
+// included. A URL built with placeholders is cut at the first placeholder.
+func (w *WDL) EmbeddedURLs() []string {
+	var urls []string
+	seen := map[string]bool{}
+	Walk(w, func(n Node) bool {
+		v, ok := n.(*valueSpec)
+		if !ok {
+			return true
+		}
+		strs := append(v.value.stringLiterals(), metaStrings(v.meta)...)
+		for _, s := range strs {
+			for _, url := range urlPattern.FindAllString(s, -1) {
+				if !seen[url] {
+					seen[url] = true
+					urls = append(urls, url)
+				}
+			}
+		}
+		return true
+	})
+	return urls
+}
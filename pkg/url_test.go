@@ -0,0 +1,26 @@
+package wdlparser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEmbeddedURLs(t *testing.T) {
+	inputPath := "testdata/embedded_url.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	expected := []string{
+		"gs://genomes/hg38/hg38.fa",
+		"gs://genomes/hg38/hg38.fa.fai",
+		"s3://genomes/hg38/",
+		"https://example.com/docs/align",
+	}
+	if diff := cmp.Diff(expected, result.EmbeddedURLs()); diff != "" {
+		t.Errorf("unexpected embedded URLs:\n%s", diff)
+	}
+}
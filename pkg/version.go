@@ -0,0 +1,55 @@
+package wdlparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A Version is a WDL version, like 1.1, parsed from the version statement of a
+// document. The development pseudo-version is newer than any released one.
+type Version struct {
+	Major, Minor int
+	Development  bool
+}
+
+// ParseVersion parses a WDL version, like 1.1 or development.
+func ParseVersion(s string) (Version, error) {
+	if s == "development" {
+		return Version{Development: true}, nil
+	}
+	parts := strings.SplitN(s, ".", 2)
+	if len(parts) != 2 {
+		return Version{}, fmt.Errorf("unknown WDL version %q", s)
+	}
+	major, errMajor := strconv.Atoi(parts[0])
+	minor, errMinor := strconv.Atoi(parts[1])
+	if errMajor != nil || errMinor != nil {
+		return Version{}, fmt.Errorf("unknown WDL version %q", s)
+	}
+	return Version{Major: major, Minor: minor}, nil
+}
+
+func (v Version) String() string {
+	if v.Development {
+		return "development"
+	}
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// AtLeast reports whether a version is the same as or newer than a released
+// version, where 1.10 is newer than 1.2.
+func (v Version) AtLeast(major, minor int) bool {
+	if v.Development {
+		return true
+	}
+	if v.Major != major {
+		return v.Major > major
+	}
+	return v.Minor >= minor
+}
+
+// ParsedVersion returns the version of a document as a Version.
+func (w *WDL) ParsedVersion() (Version, error) {
+	return ParseVersion(w.Version)
+}
@@ -0,0 +1,48 @@
+package wdlparser
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	testCases := []struct {
+		version    string
+		want       Version
+		atLeast1_2 bool
+	}{
+		{"1.0", Version{1, 0, false}, false},
+		{"1.2", Version{1, 2, false}, true},
+		{"1.10", Version{1, 10, false}, true},
+		{"2.0", Version{2, 0, false}, true},
+		{"development", Version{0, 0, true}, true},
+	}
+	for _, tc := range testCases {
+		v, err := ParseVersion(tc.version)
+		if err != nil {
+			t.Errorf("failed to parse version %q: %v", tc.version, err)
+			continue
+		}
+		if v != tc.want || v.String() != tc.version {
+			t.Errorf("parsed %q as %v, expect %v", tc.version, v, tc.want)
+		}
+		if got := v.AtLeast(1, 2); got != tc.atLeast1_2 {
+			t.Errorf(
+				"%q at least 1.2 is %v, expect %v",
+				tc.version, got, tc.atLeast1_2,
+			)
+		}
+	}
+	if _, err := ParseVersion("draft-2"); err == nil {
+		t.Errorf("expect an error parsing version draft-2")
+	}
+
+	inputPath := "testdata/version1_1.wdl"
+	result, errs := Antlr4Parse(inputPath)
+	if errs != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(errs), inputPath,
+		)
+	}
+	v, err := result.ParsedVersion()
+	if err != nil || v != (Version{1, 1, false}) {
+		t.Errorf("version of %q is %v (%v), expect 1.1", inputPath, v, err)
+	}
+}
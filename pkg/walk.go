@@ -0,0 +1,154 @@
+package wdlparser
+
+import "sort"
+
+// Walk traverses a parsed WDL document depth first in source order, calling
+// fn on every node: imports, structs and members, the workflow, its
+// declarations, calls and call inputs, scatters, conditionals, tasks and
+// their declarations and key/values. Expressions are held in those nodes as
+// RPN, and the expressions within, like elements of an array literal,
+// arguments of a function call or placeholders of a string or a command, are
+// visited as nodes of their own, along with expressions within them. If fn
+// returns false, children of the node are skipped.
+func Walk(w *WDL, fn func(Node) bool) {
+	walk(w, func(n node) bool { return fn(n) })
+}
+
+func walk(n node, fn func(node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, child := range children(n) {
+		walk(child, fn)
+	}
+}
+
+// children returns child nodes of a node sorted by their start positions.
+func children(n node) []node {
+	var nodes []node
+	switch n := n.(type) {
+	case *WDL:
+		for _, is := range n.Imports {
+			nodes = append(nodes, is)
+		}
+		for _, s := range n.Structs {
+			nodes = append(nodes, s)
+		}
+		if n.Workflow != nil {
+			nodes = append(nodes, n.Workflow)
+		}
+		for _, t := range n.Tasks {
+			nodes = append(nodes, t)
+		}
+	case *Struct:
+		nodes = appendValueSpecs(nodes, n.Members)
+	case *Workflow:
+		nodes = appendValueSpecs(nodes, n.Inputs)
+		nodes = append(nodes, n.body()...)
+		nodes = appendValueSpecs(nodes, n.Outputs)
+		nodes = appendValueSpecs(nodes, n.Meta)
+		nodes = appendValueSpecs(nodes, n.ParameterMeta)
+	case *Scatter:
+		nodes = appendExpressions(nodes, n.Collection)
+		nodes = append(nodes, n.Body...)
+	case *Conditional:
+		nodes = appendExpressions(nodes, n.Condition)
+		nodes = append(nodes, n.Body...)
+	case *Call:
+		nodes = appendValueSpecs(nodes, n.Inputs)
+	case *valueSpec:
+		nodes = appendExpressions(nodes, n.value)
+	case *expression:
+		nodes = appendExpressions(nodes, &n.rpn)
+	case *Task:
+		for _, part := range n.commandParts {
+			if e, ok := part.(*expression); ok {
+				nodes = append(nodes, e)
+			}
+		}
+		nodes = appendValueSpecs(nodes, n.Inputs)
+		nodes = appendValueSpecs(nodes, n.PrvtDecls)
+		nodes = appendValueSpecs(nodes, n.Outputs)
+		nodes = appendValueSpecs(nodes, n.Runtime)
+		nodes = appendValueSpecs(nodes, n.Meta)
+		nodes = appendValueSpecs(nodes, n.ParameterMeta)
+	}
+	sortNodes(nodes)
+	return nodes
+}
+
+// body returns private declarations, calls, scatters and conditionals at the
+// top level of a workflow body, sorted by their start positions.
+func (w *Workflow) body() []node {
+	var nodes []node
+	for _, decl := range w.PrvtDecls {
+		if !inBlock(decl) {
+			nodes = append(nodes, decl)
+		}
+	}
+	for _, c := range w.Calls {
+		if !inBlock(c) {
+			nodes = append(nodes, c)
+		}
+	}
+	nodes = append(nodes, w.Blocks...)
+	sortNodes(nodes)
+	return nodes
+}
+
+func sortNodes(nodes []node) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].getStart() < nodes[j].getStart()
+	})
+}
+
+func appendValueSpecs(nodes []node, vs []*valueSpec) []node {
+	for _, v := range vs {
+		nodes = append(nodes, v)
+	}
+	return nodes
+}
+
+// appendExpressions appends expressions within an RPN, which are those of its
+// operands parsed as expressions of their own, like array literal elements.
+func appendExpressions(nodes []node, e *exprRPN) []node {
+	if e == nil {
+		return nodes
+	}
+	for _, elem := range *e {
+		if sub, ok := elem.(*expression); ok {
+			nodes = append(nodes, sub)
+		}
+	}
+	return nodes
+}
+
+// inBlock reports whether a node is in a scatter or conditional.
+func inBlock(n node) bool {
+	switch n.getParent().(type) {
+	case *Scatter, *Conditional:
+		return true
+	}
+	return false
+}
+
+// NodeAt returns the innermost node of a parsed WDL document spanning a
+// 0-based source offset, or nil if the offset is outside of the document.
+func (w *WDL) NodeAt(offset int) Node {
+	if n := w.nodeAt(offset); n != nil {
+		return n
+	}
+	return nil
+}
+
+func (w *WDL) nodeAt(offset int) node {
+	var found node
+	walk(w, func(n node) bool {
+		if offset < n.getStart() || offset > n.getEnd() {
+			return false
+		}
+		found = n
+		return true
+	})
+	return found
+}
@@ -0,0 +1,127 @@
+package wdlparser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWalk(t *testing.T) {
+	inputPath := "testdata/workflow_scatter.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	var visited []string
+	Walk(result, func(n Node) bool {
+		switch n := n.(type) {
+		case *WDL:
+			visited = append(visited, "WDL")
+		case *Workflow:
+			visited = append(visited, "workflow "+n.name.initialName)
+		case *Scatter:
+			visited = append(visited, "scatter "+n.Variable)
+		case *Conditional:
+			visited = append(visited, "if")
+		case *Call:
+			visited = append(visited, "call "+n.name.initialName)
+		case *Task:
+			visited = append(visited, "task "+n.name.initialName)
+			return false
+		case *valueSpec:
+			visited = append(visited, fmt.Sprintf("%T %s", n, n.name.initialName))
+		}
+		return true
+	})
+	expected := []string{
+		"WDL",
+		"workflow Scatter",
+		"*wdlparser.valueSpec files",
+		"*wdlparser.valueSpec check",
+		"scatter f",
+		"*wdlparser.valueSpec name",
+		"if",
+		"call Check",
+		"*wdlparser.valueSpec file",
+		"call Merge",
+		"task Check",
+		"task Merge",
+	}
+	if diff := cmp.Diff(expected, visited); diff != "" {
+		t.Errorf("unexpected walk:\n%s", diff)
+	}
+}
+
+func TestScatterConditional(t *testing.T) {
+	inputPath := "testdata/workflow_scatter.wdl"
+	result, err := Antlr4Parse(inputPath)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), inputPath,
+		)
+	}
+
+	scatter := result.Workflow.Blocks[0].(*Scatter)
+	if diff := cmp.Diff(
+		&exprRPN{newIdentifier("files", true)},
+		scatter.Collection,
+		commonCmpopts...,
+	); diff != "" {
+		t.Errorf("unexpected scatter collection:\n%s", diff)
+	}
+	conditional := scatter.Body[1].(*Conditional)
+	if conditional.getParent() != scatter {
+		t.Errorf("conditional should be in scatter")
+	}
+	if diff := cmp.Diff(
+		&exprRPN{newIdentifier("check", true)},
+		conditional.Condition,
+		commonCmpopts...,
+	); diff != "" {
+		t.Errorf("unexpected condition:\n%s", diff)
+	}
+	if result.Workflow.Calls[0].getParent() != conditional {
+		t.Errorf("call Check should be in conditional")
+	}
+}
+
+func TestWalkExpressions(t *testing.T) {
+	input := `version 1.1
+task T {
+    input {
+        Array[Int] xs = [1, length([2, 3])]
+    }
+    command <<< echo ~{sep=" " xs} >>>
+}`
+	result, err := Antlr4Parse(input)
+	if err != nil {
+		t.Errorf(
+			"Found %d errors in %q, expect no errors", len(err), input,
+		)
+	}
+
+	var visited []string
+	Walk(result, func(n Node) bool {
+		if _, ok := n.(*expression); ok {
+			visited = append(visited, fmt.Sprintf(
+				"%s in %T", result.Source(n), n.Parent(),
+			))
+		}
+		return true
+	})
+	expected := []string{
+		"1 in *wdlparser.valueSpec",
+		"length([2, 3]) in *wdlparser.valueSpec",
+		"[2, 3] in *wdlparser.expression",
+		"2 in *wdlparser.expression",
+		"3 in *wdlparser.expression",
+		"xs in *wdlparser.Task",
+	}
+	if diff := cmp.Diff(expected, visited); diff != "" {
+		t.Errorf("unexpected walk:\n%s", diff)
+	}
+}
@@ -0,0 +1,69 @@
+package wdlparser
+
+import (
+	"github.com/antlr/antlr4/runtime/Go/antlr"
+	parser "github.com/yunhailuo/wdlparser/pkg/antlr4_grammar/1_1"
+)
+
+// Deprecated is the rule name of warnings on constructs which are still valid
+// but deprecated in WDL 1.1.
+const Deprecated = "Deprecated"
+
+// warn records a warning on source from a start token to a stop token, both
+// as a Diagnostic of the document and as a SyntaxError on the source text of
+// the tokens, which is reported under WarningsAsErrors. Either is of warning
+// severity.
+func (l *wdlv1_1Listener) warn(start, stop antlr.Token, msg string) {
+	kind := diagnosticKinds[Deprecated]
+	l.wdl.Warnings = append(l.wdl.Warnings, Diagnostic{
+		Rule:     Deprecated,
+		Start:    start.GetStart(),
+		End:      stop.GetStop(),
+		Msg:      msg,
+		Code:     kind.code,
+		Severity: kind.severity,
+	})
+	err := newSyntaxError(
+		start.GetLine(), start.GetColumn(), kind.code, msg,
+	)
+	err.Severity = kind.severity
+	err.Token = start.GetInputStream().GetText(start.GetStart(), stop.GetStop())
+	err.Stop = stop.GetStop()
+	err.EndLine, err.EndColumn = tokenEnd(
+		start.GetLine(), start.GetColumn(), err.Token,
+	)
+	l.warningErrors = append(l.warningErrors, err)
+}
+
+// warnDollarPlaceholder warns about a placeholder if its start is ${.
+func (l *wdlv1_1Listener) warnDollarPlaceholder(
+	start antlr.TerminalNode, ctx antlr.ParserRuleContext,
+) {
+	if start != nil && start.GetText() == "${" {
+		l.warn(
+			start.GetSymbol(),
+			ctx.GetStop(),
+			"${} placeholders are deprecated, use ~{} instead",
+		)
+	}
+}
+
+func (l *wdlv1_1Listener) EnterType_base(ctx *parser.Type_baseContext) {
+	if object := ctx.OBJECT(); object != nil {
+		l.warn(
+			object.GetSymbol(),
+			object.GetSymbol(),
+			"the Object type is deprecated, use a struct instead",
+		)
+	}
+}
+
+func (l *wdlv1_1Listener) EnterObject_literal(
+	ctx *parser.Object_literalContext,
+) {
+	l.warn(
+		ctx.GetStart(),
+		ctx.GetStop(),
+		"object literals are deprecated, use a struct instead",
+	)
+}
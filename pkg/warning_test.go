@@ -0,0 +1,87 @@
+package wdlparser
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWarnings(t *testing.T) {
+	input := `version 1.1
+task T {
+    input {
+        Object o
+        String s = "a ${o} b ~{o}"
+    }
+    command {
+        echo ${s} ~{s}
+    }
+}`
+	expectedWarnings := []Diagnostic{
+		{
+			Deprecated, 41, 46,
+			"the Object type is deprecated, use a struct instead",
+			CodeDeprecated, SeverityWarning,
+		},
+		{
+			Deprecated, 72, 75,
+			"${} placeholders are deprecated, use ~{} instead",
+			CodeDeprecated, SeverityWarning,
+		},
+		{
+			Deprecated, 118, 121,
+			"${} placeholders are deprecated, use ~{} instead",
+			CodeDeprecated, SeverityWarning,
+		},
+	}
+	result, errs := Antlr4Parse(input)
+	if errs != nil {
+		t.Errorf("Found %d errors, expect no errors", len(errs))
+	}
+	if diff := cmp.Diff(expectedWarnings, result.Warnings); diff != "" {
+		t.Errorf("unexpected warnings:\n%s", diff)
+	}
+
+	result, errs = ParseString(input, ParseOptions{WarningsAsErrors: true})
+	expectedErrs := []SyntaxError{
+		{
+			Line:      4,
+			Column:    8,
+			EndLine:   4,
+			EndColumn: 14,
+			Token:     "Object",
+			Stop:      46,
+			Msg:       "the Object type is deprecated, use a struct instead",
+			Code:      CodeDeprecated,
+			Severity:  SeverityWarning,
+		},
+		{
+			Line:      5,
+			Column:    22,
+			EndLine:   5,
+			EndColumn: 26,
+			Token:     "${o}",
+			Stop:      75,
+			Msg:       "${} placeholders are deprecated, use ~{} instead",
+			Code:      CodeDeprecated,
+			Severity:  SeverityWarning,
+		},
+		{
+			Line:      8,
+			Column:    13,
+			EndLine:   8,
+			EndColumn: 17,
+			Token:     "${s}",
+			Stop:      121,
+			Msg:       "${} placeholders are deprecated, use ~{} instead",
+			Code:      CodeDeprecated,
+			Severity:  SeverityWarning,
+		},
+	}
+	if diff := cmp.Diff(expectedErrs, errs); diff != "" {
+		t.Errorf("unexpected syntax errors:\n%s", diff)
+	}
+	if diff := cmp.Diff(expectedWarnings, result.Warnings); diff != "" {
+		t.Errorf("unexpected warnings:\n%s", diff)
+	}
+}